@@ -1,8 +1,16 @@
 package migration
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-universal/console"
+	"github.com/spf13/cobra"
+)
 
 // NewMigrationCLI creates a new cobra command for database migration with the provided options.
+// Commands print their result and error in the --output format (json, yaml or table)
+// and return the error from Execute, so callers can exit with non-zero code.
 func NewMigrationCLI(m Migration, options ...CLIOptions) *cobra.Command {
 	option := newCLIOption()
 	for _, opt := range options {
@@ -19,7 +27,21 @@ func NewMigrationCLI(m Migration, options ...CLIOptions) *cobra.Command {
 			}
 			return nil
 		},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(cmd); err != nil {
+				console.Message().Red("Migration").Italic().Print(err.Error())
+				return err
+			}
+			return nil
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
 	}
+	cmd.PersistentFlags().StringP("output", "o", outputTable, "output format (json, yaml or table)")
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		console.Message().Red("Migration").Italic().Print(err.Error())
+		return err
+	})
 	if option.create {
 		cmd.AddCommand(cmdNew(m, option))
 	}
@@ -27,5 +49,61 @@ func NewMigrationCLI(m Migration, options ...CLIOptions) *cobra.Command {
 	cmd.AddCommand(cmdDown(m, option))
 	cmd.AddCommand(cmdRefresh(m, option))
 	cmd.AddCommand(cmdSummary(m, option))
+	cmd.AddCommand(cmdStatus(m, option))
+	cmd.AddCommand(cmdVerify(m, option))
+	cmd.AddCommand(cmdImport(m, option))
+	cmd.AddCommand(cmdLint(m, option))
+	cmd.AddCommand(cmdAnalyze(m, option))
+	cmd.AddCommand(cmdCheck(m, option))
+	cmd.AddCommand(cmdMark(m, option))
+	cmd.AddCommand(cmdUnmark(m, option))
+	cmd.AddCommand(cmdSquash(m, option))
+	if option.seeder != nil {
+		cmd.AddCommand(cmdSeed(option))
+	}
 	return cmd
 }
+
+// printPlan pretty prints the dry-run plan in execution order.
+func printPlan(plan Plan) {
+	console.PrintF("@Bwb{ Migration Plan: }\n")
+	if plan.IsEmpty() {
+		console.Message().Indent().Italic().Print("nothing to run")
+		return
+	}
+
+	stage := ""
+	for i, step := range plan {
+		if step.Stage != stage {
+			if stage != "" {
+				fmt.Println()
+			}
+			stage = step.Stage
+			console.PrintF("@BUb{%s} @b{Stage}:\n", strings.ToTitle(stage))
+		}
+
+		if step.Repeatable && step.Direction == DirectionDown {
+			console.PrintF("    @g{%d. CLEAR:} @I{%s} @y{(repeatable, reruns on next up)}\n", i+1, step.Name)
+			continue
+		} else if step.Repeatable {
+			console.PrintF("    @g{%d. REPEATABLE:} @I{%s}\n", i+1, step.Name)
+		} else if step.Baseline {
+			console.PrintF("    @g{%d. BASELINE:} @I{%s} @y{(mark only)}\n", i+1, step.Name)
+		} else if step.NoTransaction {
+			console.PrintF("    @g{%d. %s:} @I{%s} @y{(no transaction)}\n", i+1, strings.ToUpper(string(step.Direction)), step.Name)
+		} else {
+			console.PrintF("    @g{%d. %s:} @I{%s}\n", i+1, strings.ToUpper(string(step.Direction)), step.Name)
+		}
+		if step.IsFunc() {
+			console.PrintF("        @I{-- go function}\n")
+			continue
+		} else if step.Script == "" {
+			console.PrintF("        @I{-- empty script}\n")
+			continue
+		}
+		for _, line := range strings.Split(step.Script, "\n") {
+			fmt.Printf("        %s\n", line)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,66 @@
+package migration
+
+import (
+	"fmt"
+
+	"github.com/go-universal/console"
+	"github.com/spf13/cobra"
+)
+
+func cmdAnalyze(m Migration, option *cliOption) *cobra.Command {
+	analyzeCmd := &cobra.Command{}
+	analyzeCmd.Use = "analyze [stage1, stage2, ...]"
+	analyzeCmd.Short = "report postgres statements taking long locks, fails when found"
+	analyzeCmd.Flags().Bool("pending", false, "analyze pending migrations only")
+	analyzeCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if option.callback != nil {
+			defer option.callback()
+		}
+
+		findings, err := m.AnalyzeContext(cmd.Context(), args...)
+		if err != nil {
+			return report(cmd, "Analyze", nil, err, nil)
+		}
+
+		// Filter applied migrations
+		if getBoolFlag(cmd, "pending") {
+			status, err := m.StatusContext(cmd.Context())
+			if err != nil {
+				return report(cmd, "Analyze", nil, err, nil)
+			}
+
+			pending := make(map[string]bool)
+			for _, entry := range status.Pending() {
+				pending[entry.Name+"\x00"+entry.Stage] = true
+			}
+
+			filtered := make([]Finding, 0)
+			for _, finding := range findings {
+				if pending[finding.Name+"\x00"+finding.Stage] {
+					filtered = append(filtered, finding)
+				}
+			}
+			findings = filtered
+		}
+
+		if len(findings) > 0 {
+			err = fmt.Errorf("%d unsafe statements found", len(findings))
+		}
+
+		return report(cmd, "Analyze", findings, err, func() {
+			if len(findings) == 0 {
+				console.Message().Green("Analyze").Italic().Print("no unsafe statement found")
+				return
+			}
+
+			console.PrintF("@Bwb{ Unsafe Statements: }\n")
+			for _, finding := range findings {
+				console.PrintF("    @r{%s:} @I{%s} (%s, line %d)\n", finding.Rule, finding.Name, finding.Stage, finding.Line)
+				console.PrintF("        %s\n", finding.Statement)
+				console.PrintF("        @y{%s}, @g{%s}\n", finding.Message, finding.Suggestion)
+			}
+			fmt.Println()
+		})
+	}
+	return analyzeCmd
+}
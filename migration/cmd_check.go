@@ -0,0 +1,78 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-universal/console"
+	"github.com/spf13/cobra"
+)
+
+func cmdCheck(m Migration, option *cliOption) *cobra.Command {
+	checkCmd := &cobra.Command{}
+	checkCmd.Use = "check [stage1, stage2, ...]"
+	checkCmd.Short = "check pending migrations are reversible in a rollback-only transaction"
+	checkCmd.Flags().StringP("name", "n", "", "migration name, directory qualified name or glob")
+	checkCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if option.callback != nil {
+			defer option.callback()
+		}
+
+		stages := append([]string{}, args...)
+		if len(stages) == 0 {
+			stages = option.stages.Elements()
+		}
+
+		if len(stages) == 0 {
+			return report(cmd, "Check", nil, errors.New("no stage stage specified"), nil)
+		}
+
+		options := make([]MigrationOption, 0)
+		if name := getFlag(cmd, "name"); name != "" {
+			options = append(options, OnlyFiles(name))
+		}
+		if option.scratch != nil {
+			options = append(options, Scratch(option.scratch))
+		}
+
+		result, err := m.CheckReversibleContext(cmd.Context(), stages, options...)
+		failures := 0
+		for _, item := range result {
+			if !item.Reversible() {
+				failures++
+			}
+		}
+		if err == nil && failures > 0 {
+			err = fmt.Errorf("%d migrations are not reversible", failures)
+		}
+
+		return report(cmd, "Check", result, err, func() {
+			console.PrintF("@Bwb{ Reversibility Check: }\n")
+			if len(result) == 0 {
+				console.Message().Indent().Italic().Print("nothing to check")
+				return
+			}
+
+			for _, item := range result {
+				if item.Reversible() {
+					console.PrintF("    @g{REVERSIBLE:} @I{%s} (%s)\n", item.Name, item.Stage)
+					continue
+				}
+
+				console.PrintF("    @r{FAILED:} @I{%s} (%s)\n", item.Name, item.Stage)
+				if item.Error != "" {
+					console.PrintF("        @y{%s}\n", item.Error)
+				}
+				for _, object := range item.Missing {
+					console.PrintF("        @r{- %s}\n", strings.TrimSpace(object))
+				}
+				for _, object := range item.Leftover {
+					console.PrintF("        @g{+ %s}\n", strings.TrimSpace(object))
+				}
+			}
+			fmt.Println()
+		})
+	}
+	return checkCmd
+}
@@ -1,6 +1,7 @@
 package migration
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -12,8 +13,13 @@ func cmdDown(m Migration, option *cliOption) *cobra.Command {
 	downCmd := &cobra.Command{}
 	downCmd.Use = "down [stage1, stage2, ...]"
 	downCmd.Short = "rollback migrations"
-	downCmd.Flags().StringP("name", "n", "", "migration name")
-	downCmd.Run = func(cmd *cobra.Command, args []string) {
+	downCmd.Flags().StringP("name", "n", "", "migration name, directory qualified name or glob")
+	downCmd.Flags().Int("steps", 0, "number of files to roll back")
+	downCmd.Flags().Int64("to", 0, "roll back files after the timestamp")
+	downCmd.Flags().Int("batches", 0, "number of last batches to roll back")
+	downCmd.Flags().Bool("dry-run", false, "print scripts without running them")
+	addTenantFlags(downCmd, option)
+	downCmd.RunE = func(cmd *cobra.Command, args []string) error {
 		if option.callback != nil {
 			defer option.callback()
 		}
@@ -24,27 +30,58 @@ func cmdDown(m Migration, option *cliOption) *cobra.Command {
 		}
 
 		if len(stages) == 0 {
-			console.Message().
-				Red("Down").Italic().
-				Print("no stage stage specified")
-			return
+			return report(cmd, "Down", nil, errors.New("no stage stage specified"), nil)
 		}
 
 		options := make([]MigrationOption, 0)
 		if name := getFlag(cmd, "name"); name != "" {
 			options = append(options, OnlyFiles(name))
 		}
+		if steps := getIntFlag(cmd, "steps"); steps > 0 {
+			options = append(options, Steps(steps))
+		}
+		if cmd.Flags().Changed("to") {
+			to, _ := cmd.Flags().GetInt64("to")
+			options = append(options, Target(to))
+		}
+		if batches := getIntFlag(cmd, "batches"); batches > 0 {
+			options = append(options, Batches(batches))
+		}
+
+		if tenants, ok := tenantFlags(cmd, option); ok {
+			if getBoolFlag(cmd, "dry-run") {
+				return report(cmd, "Down", nil, errTenantDryRun, nil)
+			}
 
-		result, err := m.Down(stages, options...)
-		if err != nil {
-			console.Message().Red("Down").Italic().Print(err.Error())
-			return
+			result, err := option.tenants.Down(cmd.Context(), tenants, stages, options...)
+			return reportTenants(cmd, "Down", "DOWN", result, err)
 		}
 
-		console.PrintF("@Bwb{ Rollback Summery: }\n")
-		if result.IsEmpty() {
-			console.Message().Indent().Italic().Print("nothing to roll back")
-		} else {
+		if getBoolFlag(cmd, "dry-run") {
+			plan := make(Plan, 0)
+			options = append(options, DryRun(&plan))
+			if _, err := m.DownContext(cmd.Context(), stages, options...); err != nil {
+				return report(cmd, "Down", nil, err, nil)
+			}
+
+			return report(cmd, "Down", plan, nil, func() { printPlan(plan) })
+		}
+
+		result, err := m.DownContext(cmd.Context(), stages, options...)
+		if result == nil {
+			result = make(Summary, 0)
+		}
+		if err != nil && result.IsEmpty() {
+			return report(cmd, "Down", result, err, nil)
+		}
+
+		return report(cmd, "Down", result, err, func() {
+			console.PrintF("@Bwb{ Rollback Summery: }\n")
+			if result.IsEmpty() {
+				console.Message().Indent().Italic().Print("nothing to roll back")
+				return
+			}
+
 			for stage, files := range result.GroupByStage() {
 				console.PrintF("@BUb{%s} @b{Stage} @Ib{(%d Files)}:\n", strings.ToTitle(stage), len(files))
 				for _, file := range files {
@@ -53,7 +90,7 @@ func cmdDown(m Migration, option *cliOption) *cobra.Command {
 
 				fmt.Println()
 			}
-		}
+		})
 	}
 
 	return downCmd
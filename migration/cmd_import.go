@@ -0,0 +1,90 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-universal/console"
+	"github.com/go-universal/fs"
+	"github.com/spf13/cobra"
+)
+
+// importResult is the result of the import command.
+type importResult struct {
+	Files    []string `json:"files" yaml:"files"`       // Written migration files.
+	Imported Summary  `json:"imported" yaml:"imported"` // Entries recorded from the history table.
+}
+
+func cmdImport(m Migration, option *cliOption) *cobra.Command {
+	importCmd := &cobra.Command{}
+	importCmd.Use = "import [directory]"
+	importCmd.Short = "converts golang-migrate, goose or flyway migrations into the output path"
+	importCmd.Args = reportArgs("Import", cobra.ExactArgs(1))
+	importCmd.Flags().StringP("format", "f", "", "source layout (golang-migrate, goose or flyway)")
+	importCmd.Flags().StringP("stage", "s", "", "stage of imported migrations (defaults to first default stage or main)")
+	importCmd.Flags().Bool("history", false, "record migrations applied by the source tool history table")
+	importCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if option.callback != nil {
+			defer option.callback()
+		}
+
+		if option.root == "" {
+			return report(cmd, "Import", nil, errors.New("output path must be specified using the WithOutputPath option"), nil)
+		}
+
+		stage := strings.TrimSpace(getFlag(cmd, "stage"))
+		if stage == "" {
+			if stages := option.stages.Elements(); len(stages) > 0 {
+				stage = stages[0]
+			} else {
+				stage = "main"
+			}
+		}
+
+		var format FileFormat
+		switch strings.ToLower(getFlag(cmd, "format")) {
+		case "golang-migrate", "migrate":
+			format = GolangMigrateFormat(stage)
+		case "goose":
+			format = GooseFormat(stage)
+		case "flyway":
+			format = FlywayFormat(stage)
+		default:
+			return report(cmd, "Import", nil, errors.New("format must be one of golang-migrate, goose or flyway"), nil)
+		}
+
+		// Convert files
+		result := importResult{Imported: make(Summary, 0)}
+		names, err := ConvertMigrationFiles(fs.NewDir(args[0]), ".", format, option.root, m.Extension())
+		result.Files = names
+		if err == nil && getBoolFlag(cmd, "history") && len(names) > 0 {
+			// Seed migrations table
+			if err = m.Load(); err == nil {
+				result.Imported, err = m.ImportContext(cmd.Context(), format, names...)
+			}
+		}
+
+		return report(cmd, "Import", result, err, func() {
+			console.PrintF("@Bwb{ Import Summery: }\n")
+			if len(result.Files) == 0 {
+				console.Message().Indent().Italic().Print("nothing to import")
+				return
+			}
+			for _, name := range result.Files {
+				console.PrintF("    @g{CONVERTED:} @I{%s}\n", name)
+			}
+			fmt.Println()
+
+			for stage, files := range result.Imported.GroupByStage() {
+				console.PrintF("@BUb{%s} @b{Stage} @Ib{(%d Files)}:\n", strings.ToTitle(stage), len(files))
+				for _, file := range files {
+					console.PrintF("    @g{MARKED:} @I{%s}\n", file.Name)
+				}
+
+				fmt.Println()
+			}
+		})
+	}
+	return importCmd
+}
@@ -0,0 +1,47 @@
+package migration
+
+import (
+	"fmt"
+
+	"github.com/go-universal/console"
+	"github.com/spf13/cobra"
+)
+
+func cmdLint(m Migration, option *cliOption) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lint [stage1, stage2, ...]",
+		Short: "check migration files, fails when issues found",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if option.callback != nil {
+				defer option.callback()
+			}
+
+			stages := append([]string{}, args...)
+			if len(stages) == 0 {
+				stages = option.stages.Elements()
+			}
+
+			issues, err := m.LintContext(cmd.Context(), stages...)
+			if err != nil {
+				return report(cmd, "Lint", nil, err, nil)
+			}
+
+			if len(issues) > 0 {
+				err = fmt.Errorf("%d lint issues found", len(issues))
+			}
+
+			return report(cmd, "Lint", issues, err, func() {
+				if len(issues) == 0 {
+					console.Message().Green("Lint").Italic().Print("no issue found")
+					return
+				}
+
+				console.PrintF("@Bwb{ Lint Issues: }\n")
+				for _, issue := range issues {
+					console.PrintF("    @y{%s:} @I{%s}\n", issue.Rule, issue.String())
+				}
+				fmt.Println()
+			})
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package migration
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-universal/console"
+	"github.com/spf13/cobra"
+)
+
+// errConfirmRequired is returned when confirmation can not be prompted on structured output.
+var errConfirmRequired = errors.New("confirmation required, pass --yes")
+
+func cmdMark(m Migration, option *cliOption) *cobra.Command {
+	return markCommand(option, "mark", "Mark", "MARK", "record migrations as applied without running them", m.MarkContext)
+}
+
+func cmdUnmark(m Migration, option *cliOption) *cobra.Command {
+	return markCommand(option, "unmark", "Unmark", "UNMARK", "remove applied migrations records without rolling them back", m.UnmarkContext)
+}
+
+// markCommand builds the mark and unmark commands around the migration method.
+func markCommand(
+	option *cliOption, use, title, tag, short string,
+	run func(ctx context.Context, stages []string, options ...MigrationOption) (Summary, error),
+) *cobra.Command {
+	markCmd := &cobra.Command{}
+	markCmd.Use = use + " [stage1, stage2, ...]"
+	markCmd.Short = short
+	markCmd.Flags().StringP("name", "n", "", "migration name, directory qualified name or glob")
+	markCmd.Flags().Int("steps", 0, "number of files to "+use)
+	markCmd.Flags().Int64("to", 0, "limit files by the timestamp")
+	markCmd.Flags().BoolP("yes", "y", false, "skip confirmation")
+	markCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if option.callback != nil {
+			defer option.callback()
+		}
+
+		stages := append([]string{}, args...)
+		if len(stages) == 0 {
+			stages = option.stages.Elements()
+		}
+
+		if len(stages) == 0 {
+			return report(cmd, title, nil, errors.New("no stage stage specified"), nil)
+		}
+
+		options := make([]MigrationOption, 0)
+		if name := getFlag(cmd, "name"); name != "" {
+			options = append(options, OnlyFiles(name))
+		}
+		if steps := getIntFlag(cmd, "steps"); steps > 0 {
+			options = append(options, Steps(steps))
+		}
+		if cmd.Flags().Changed("to") {
+			to, _ := cmd.Flags().GetInt64("to")
+			options = append(options, Target(to))
+		}
+
+		// Confirm
+		if !getBoolFlag(cmd, "yes") {
+			if getFlag(cmd, "output") != outputTable {
+				return report(cmd, title, nil, errConfirmRequired, nil)
+			}
+
+			plan := make(Plan, 0)
+			if _, err := run(cmd.Context(), stages, append(options, DryRun(&plan))...); err != nil {
+				return report(cmd, title, nil, err, nil)
+			}
+
+			if plan.IsEmpty() {
+				console.Message().Indent().Italic().Print("nothing to " + use)
+				return nil
+			}
+
+			for _, step := range plan {
+				console.PrintF("    @y{%s:} @I{%s} @I{(%s)}\n", tag, step.Name, step.Stage)
+			}
+			if !confirm(cmd, "Continue?") {
+				console.Message().Indent().Italic().Print("aborted")
+				return nil
+			}
+		}
+
+		result, err := run(cmd.Context(), stages, options...)
+		if result == nil {
+			result = make(Summary, 0)
+		}
+
+		return report(cmd, title, result, err, func() {
+			if err != nil {
+				return
+			}
+
+			console.PrintF("@Bwb{ %s Summery: }\n", title)
+			if result.IsEmpty() {
+				console.Message().Indent().Italic().Print("nothing to " + use)
+				return
+			}
+
+			for stage, files := range result.GroupByStage() {
+				console.PrintF("@BUb{%s} @b{Stage} @Ib{(%d Files)}:\n", strings.ToTitle(stage), len(files))
+				for _, file := range files {
+					console.PrintF("    @g{%s:} @I{%s}\n", tag, file.Name)
+				}
+
+				fmt.Println()
+			}
+		})
+	}
+
+	return markCmd
+}
+
+// confirm prompts the question and reports whether the answer is yes.
+func confirm(cmd *cobra.Command, question string) bool {
+	fmt.Fprintf(cmd.OutOrStdout(), "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
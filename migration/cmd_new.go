@@ -1,6 +1,7 @@
 package migration
 
 import (
+	"errors"
 	"path"
 	"strings"
 
@@ -12,17 +13,14 @@ func cmdNew(m Migration, option *cliOption) *cobra.Command {
 	return &cobra.Command{
 		Use:   "new [name]",
 		Short: "Create a new migration file with default stages in the output path",
-		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
+		Args:  reportArgs("Create", cobra.MinimumNArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if option.callback != nil {
 				defer option.callback()
 			}
 
 			if option.root == "" {
-				console.Message().
-					Red("Create").Italic().
-					Print("output path must be specified using the WithOutputPath option")
-				return
+				return report(cmd, "Create", nil, errors.New("output path must be specified using the WithOutputPath option"), nil)
 			}
 
 			name := strings.TrimSpace(path.Base(args[0]))
@@ -32,10 +30,7 @@ func cmdNew(m Migration, option *cliOption) *cobra.Command {
 			}
 
 			if name == "" {
-				console.Message().
-					Red("Create").Italic().
-					Print("file name cannot be empty")
-				return
+				return report(cmd, "Create", nil, errors.New("file name cannot be empty"), nil)
 			}
 
 			err := CreateMigrationFile(
@@ -44,15 +39,14 @@ func cmdNew(m Migration, option *cliOption) *cobra.Command {
 				option.stages.Elements()...,
 			)
 			if err != nil {
-				console.Message().
-					Red("Create").
-					Italic().Print(err.Error())
-				return
+				return report(cmd, "Create", nil, err, nil)
 			}
 
-			console.Message().
-				Green("Create").Italic().
-				Printf(`"%s" migration file created`, name)
+			return report(cmd, "Create", name, nil, func() {
+				console.Message().
+					Green("Create").Italic().
+					Printf(`"%s" migration file created`, name)
+			})
 		},
 	}
 }
@@ -7,6 +7,9 @@ type cliOption struct {
 	refreshes *optionSet
 	only      *optionSet
 	exclude   *optionSet
+	seeder    Seeder
+	tenants   TenantRunner
+	scratch   MigrationSource
 	callback  func()
 }
 
@@ -72,3 +75,25 @@ func WithCallback(cb func()) CLIOptions {
 		o.callback = cb
 	}
 }
+
+// WithSeeder enables the seed command to apply seed files using the seeder.
+func WithSeeder(seeder Seeder) CLIOptions {
+	return func(o *cliOption) {
+		o.seeder = seeder
+	}
+}
+
+// WithTenantRunner enables the --tenants and --all-tenants flags of up and down commands
+// to migrate tenant databases using the runner.
+func WithTenantRunner(runner TenantRunner) CLIOptions {
+	return func(o *cliOption) {
+		o.tenants = runner
+	}
+}
+
+// WithScratchSource runs the check command on a disposable database, required on MySQL.
+func WithScratchSource(source MigrationSource) CLIOptions {
+	return func(o *cliOption) {
+		o.scratch = source
+	}
+}
@@ -0,0 +1,87 @@
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/go-universal/console"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// cliOutput is the document printed by the json and yaml output formats.
+type cliOutput struct {
+	Command string `json:"command" yaml:"command"`
+	Result  any    `json:"result" yaml:"result"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// validateOutput checks the --output flag value.
+func validateOutput(cmd *cobra.Command) error {
+	switch getFlag(cmd, "output") {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf("invalid output %q, expected json, yaml or table", getFlag(cmd, "output"))
+	}
+}
+
+// report prints the command result and error in the --output format and returns the error,
+// so the command exits with non-zero code on failure.
+// On table output, the error is printed as message and print (if not nil) prints the result.
+func report(cmd *cobra.Command, title string, result any, err error, print func()) error {
+	format := getFlag(cmd, "output")
+	if format == outputJSON || format == outputYAML {
+		doc := cliOutput{Command: cmd.Name(), Result: result}
+		if err != nil {
+			doc.Error = err.Error()
+		}
+
+		if e := writeDocument(cmd.OutOrStdout(), format, doc); e != nil {
+			return e
+		}
+		return err
+	}
+
+	if err != nil {
+		console.Message().Red(title).Italic().Print(err.Error())
+	}
+	if print != nil {
+		print()
+	}
+	return err
+}
+
+// reportArgs wraps the positional arguments validator to report its error in the --output format.
+func reportArgs(title string, validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return report(cmd, title, nil, err, nil)
+		}
+		return nil
+	}
+}
+
+// writeDocument encodes the document as json or yaml.
+func writeDocument(w io.Writer, format string, doc any) error {
+	if format == outputYAML {
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
@@ -1,6 +1,7 @@
 package migration
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -12,8 +13,9 @@ func cmdRefresh(m Migration, option *cliOption) *cobra.Command {
 	reCmd := &cobra.Command{}
 	reCmd.Use = "refresh [stage1, stage2, ...]"
 	reCmd.Short = "refresh migrations"
-	reCmd.Flags().StringP("name", "n", "", "migration name")
-	reCmd.Run = func(cmd *cobra.Command, args []string) {
+	reCmd.Flags().StringP("name", "n", "", "migration name, directory qualified name or glob")
+	reCmd.Flags().Bool("dry-run", false, "print scripts without running them")
+	reCmd.RunE = func(cmd *cobra.Command, args []string) error {
 		if option.callback != nil {
 			defer option.callback()
 		}
@@ -24,10 +26,7 @@ func cmdRefresh(m Migration, option *cliOption) *cobra.Command {
 		}
 
 		if len(stages) == 0 {
-			console.Message().
-				Red("Refresh").Italic().
-				Print("no stage stage specified")
-			return
+			return report(cmd, "Refresh", nil, errors.New("no stage stage specified"), nil)
 		}
 
 		options := make([]MigrationOption, 0)
@@ -35,16 +34,31 @@ func cmdRefresh(m Migration, option *cliOption) *cobra.Command {
 			options = append(options, OnlyFiles(name))
 		}
 
-		result, err := m.Refresh(stages, options...)
-		if err != nil {
-			console.Message().Red("Refresh").Italic().Print(err.Error())
-			return
+		if getBoolFlag(cmd, "dry-run") {
+			plan := make(Plan, 0)
+			options = append(options, DryRun(&plan))
+			if _, err := m.RefreshContext(cmd.Context(), stages, options...); err != nil {
+				return report(cmd, "Refresh", nil, err, nil)
+			}
+
+			return report(cmd, "Refresh", plan, nil, func() { printPlan(plan) })
+		}
+
+		result, err := m.RefreshContext(cmd.Context(), stages, options...)
+		if result == nil {
+			result = make(Summary, 0)
 		}
+		if err != nil && result.IsEmpty() {
+			return report(cmd, "Refresh", result, err, nil)
+		}
+
+		return report(cmd, "Refresh", result, err, func() {
+			console.PrintF("@Bwb{ Refresh Summery: }\n")
+			if result.IsEmpty() {
+				console.Message().Indent().Italic().Print("nothing to refresh")
+				return
+			}
 
-		console.PrintF("@Bwb{ Refresh Summery: }\n")
-		if result.IsEmpty() {
-			console.Message().Indent().Italic().Print("nothing to refresh")
-		} else {
 			for stage, files := range result.GroupByStage() {
 				console.PrintF("@BUb{%s} @b{Stage} @Ib{(%d Files)}:\n", strings.ToTitle(stage), len(files))
 				for _, file := range files {
@@ -53,7 +67,7 @@ func cmdRefresh(m Migration, option *cliOption) *cobra.Command {
 
 				fmt.Println()
 			}
-		}
+		})
 	}
 
 	return reCmd
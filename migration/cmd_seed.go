@@ -0,0 +1,41 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-universal/console"
+	"github.com/spf13/cobra"
+)
+
+func cmdSeed(option *cliOption) *cobra.Command {
+	seedCmd := &cobra.Command{}
+	seedCmd.Use = "seed"
+	seedCmd.Short = "applies pending seed files"
+	seedCmd.Flags().StringP("env", "e", "", "environment of seed files to include (e.g. dev), files without environment always run")
+	seedCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if option.callback != nil {
+			defer option.callback()
+		}
+
+		env := strings.TrimSpace(getFlag(cmd, "env"))
+		result, err := option.seeder.SeedContext(cmd.Context(), env)
+		if err != nil {
+			return report(cmd, "Seed", nil, err, nil)
+		}
+
+		return report(cmd, "Seed", result, nil, func() {
+			console.PrintF("@Bwb{ Seed Summery: }\n")
+			if len(result) == 0 {
+				console.Message().Indent().Italic().Print("nothing to seed")
+				return
+			}
+
+			for _, item := range result {
+				console.PrintF("    @g{SEED:} @I{%s} @I{(%d rows into %s)}\n", item.Name, item.Rows, item.Table)
+			}
+			fmt.Println()
+		})
+	}
+	return seedCmd
+}
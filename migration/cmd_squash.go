@@ -0,0 +1,80 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-universal/console"
+	"github.com/spf13/cobra"
+)
+
+func cmdSquash(m Migration, option *cliOption) *cobra.Command {
+	squashCmd := &cobra.Command{}
+	squashCmd.Use = "squash"
+	squashCmd.Short = "combine migration files up to a timestamp into baseline files"
+	squashCmd.Flags().Int64("to", 0, "squash files with timestamp up to the value")
+	squashCmd.Flags().BoolP("yes", "y", false, "skip confirmation")
+	squashCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if option.callback != nil {
+			defer option.callback()
+		}
+
+		if option.root == "" {
+			return report(cmd, "Squash", nil, errors.New("output path must be specified using the WithOutputPath option"), nil)
+		}
+
+		if !cmd.Flags().Changed("to") {
+			return report(cmd, "Squash", nil, errors.New("timestamp must be specified using the --to flag"), nil)
+		}
+		to, _ := cmd.Flags().GetInt64("to")
+
+		// Confirm
+		if !getBoolFlag(cmd, "yes") {
+			if getFlag(cmd, "output") != outputTable {
+				return report(cmd, "Squash", nil, errConfirmRequired, nil)
+			}
+
+			plan := make(Plan, 0)
+			preview, err := m.Squash(to, option.root, DryRun(&plan))
+			if err != nil {
+				return report(cmd, "Squash", nil, err, nil)
+			}
+
+			if len(preview.Squashed) == 0 {
+				console.Message().Indent().Italic().Print("nothing to squash")
+				return nil
+			}
+
+			for _, name := range preview.Squashed {
+				console.PrintF("    @y{REMOVE:} @I{%s}\n", name)
+			}
+			for _, name := range preview.Files {
+				console.PrintF("    @g{CREATE:} @I{%s}\n", name)
+			}
+			if !confirm(cmd, "Continue?") {
+				console.Message().Indent().Italic().Print("aborted")
+				return nil
+			}
+		}
+
+		result, err := m.Squash(to, option.root)
+		return report(cmd, "Squash", result, err, func() {
+			if err != nil {
+				return
+			}
+
+			console.PrintF("@Bwb{ Squash Summery: }\n")
+			if len(result.Squashed) == 0 {
+				console.Message().Indent().Italic().Print("nothing to squash")
+				return
+			}
+
+			console.PrintF("    @I{%d files squashed}\n", len(result.Squashed))
+			for _, name := range result.Files {
+				console.PrintF("    @g{BASELINE:} @I{%s}\n", name)
+			}
+			fmt.Println()
+		})
+	}
+	return squashCmd
+}
@@ -0,0 +1,56 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/go-universal/console"
+	"github.com/spf13/cobra"
+)
+
+func cmdStatus(m Migration, option *cliOption) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "show applied, pending and orphaned migrations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if option.callback != nil {
+				defer option.callback()
+			}
+
+			result, err := m.StatusContext(cmd.Context())
+			if err != nil {
+				return report(cmd, "Status", nil, err, nil)
+			}
+
+			return report(cmd, "Status", result, nil, func() {
+				if result.IsEmpty() {
+					console.Message().Blue("Status").Italic().Print("no migration found!")
+					return
+				}
+
+				console.PrintF(
+					"@Bwb{ Migration Status: } @g{%d applied}, @y{%d pending}, @r{%d orphaned}\n",
+					len(result.Applied()), len(result.Pending()), len(result.Orphaned()),
+				)
+				groups := result.GroupByStage()
+				for _, stage := range result.Stages() {
+					files := groups[stage]
+					console.PrintF("@BUb{%s} @b{Stage} @Ib{(%d Files)}:\n", strings.ToTitle(stage), len(files))
+					for _, file := range files {
+						switch file.State {
+						case StateApplied:
+							console.PrintF("    @g{APPLIED:} @I{%s} (%s)\n", file.Name, humanize.Time(file.AppliedAt))
+						case StatePending:
+							console.PrintF("    @y{PENDING:} @I{%s}\n", file.Name)
+						case StateOrphaned:
+							console.PrintF("    @r{ORPHANED:} @I{%s} (%s)\n", file.Name, humanize.Time(file.AppliedAt))
+						}
+					}
+
+					fmt.Println()
+				}
+			})
+		},
+	}
+}
@@ -13,31 +13,47 @@ func cmdSummary(m Migration, option *cliOption) *cobra.Command {
 	return &cobra.Command{
 		Use:   "summary",
 		Short: "show migration summary",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if option.callback != nil {
 				defer option.callback()
 			}
 
-			summary, err := m.Summary()
+			summary, err := m.SummaryContext(cmd.Context())
 			if err != nil {
-				console.Message().Red("Summary").Italic().Print(err.Error())
-				return
+				return report(cmd, "Summary", nil, err, nil)
 			}
 
-			if summary.IsEmpty() {
-				console.Message().Blue("Summary").Italic().Print("nothing migrated!")
-				return
-			}
-
-			console.PrintF("@Bwb{ Migration Summery: }\n")
-			for stage, files := range summary.GroupByStage() {
-				console.PrintF("@BUb{%s} @b{Stage} @Ib{(%d Files)}:\n", strings.ToTitle(stage), len(files))
-				for _, file := range files {
-					console.PrintF("    @g{%s}: @I{%s}\n", file.Name, humanize.Time(file.CreatedAt))
+			return report(cmd, "Summary", summary, nil, func() {
+				if summary.IsEmpty() {
+					console.Message().Blue("Summary").Italic().Print("nothing migrated!")
+					return
 				}
 
-				fmt.Println()
-			}
+				console.PrintF("@Bwb{ Migration Summery: }\n")
+				for stage, files := range summary.GroupByStage() {
+					console.PrintF("@BUb{%s} @b{Stage} @Ib{(%d Files)}:\n", strings.ToTitle(stage), len(files))
+					for _, file := range files {
+						details := make([]string, 0)
+						if file.AppliedBy != "" || file.Host != "" {
+							details = append(details, "by "+strings.Trim(file.AppliedBy+"@"+file.Host, "@"))
+						}
+						if file.Version != "" {
+							details = append(details, file.Version)
+						}
+						if file.Duration > 0 {
+							details = append(details, file.Duration.String())
+						}
+
+						if len(details) > 0 {
+							console.PrintF("    @g{%s}: @I{%s} (%s)\n", file.Name, humanize.Time(file.CreatedAt), strings.Join(details, ", "))
+						} else {
+							console.PrintF("    @g{%s}: @I{%s}\n", file.Name, humanize.Time(file.CreatedAt))
+						}
+					}
+
+					fmt.Println()
+				}
+			})
 		},
 	}
 }
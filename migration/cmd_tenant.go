@@ -0,0 +1,78 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-universal/console"
+	"github.com/spf13/cobra"
+)
+
+// tenantOutput is the tenant result printed by the json and yaml output formats.
+type tenantOutput struct {
+	Tenant string  `json:"tenant" yaml:"tenant"`
+	Result Summary `json:"result" yaml:"result"`
+	Error  string  `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// addTenantFlags adds the tenant selection flags when a tenant runner is configured.
+func addTenantFlags(cmd *cobra.Command, option *cliOption) {
+	if option.tenants != nil {
+		cmd.Flags().StringSlice("tenants", nil, "comma separated tenants to migrate")
+		cmd.Flags().Bool("all-tenants", false, "migrate all discovered tenants")
+	}
+}
+
+// tenantFlags returns the tenants selected by flags, empty for all tenants.
+// Returns false if the command doesn't run in tenant mode.
+func tenantFlags(cmd *cobra.Command, option *cliOption) ([]string, bool) {
+	if option.tenants == nil {
+		return nil, false
+	}
+
+	if getBoolFlag(cmd, "all-tenants") {
+		return nil, true
+	}
+
+	tenants, _ := cmd.Flags().GetStringSlice("tenants")
+	return tenants, len(tenants) > 0
+}
+
+// reportTenants prints the tenant report and returns the joined tenant errors.
+func reportTenants(cmd *cobra.Command, title, label string, result TenantReport, err error) error {
+	if err != nil {
+		return report(cmd, title, nil, err, nil)
+	}
+
+	if failed := result.Failed(); len(failed) > 0 {
+		err = fmt.Errorf("%d of %d tenants failed", len(failed), len(result))
+	}
+
+	output := make([]tenantOutput, 0, len(result))
+	for _, item := range result {
+		out := tenantOutput{Tenant: item.Tenant, Result: item.Result}
+		if out.Result == nil {
+			out.Result = make(Summary, 0)
+		}
+		if item.Err != nil {
+			out.Error = item.Err.Error()
+		}
+		output = append(output, out)
+	}
+
+	return report(cmd, title, output, err, func() {
+		console.PrintF("@Bwb{ Tenants Summery: }\n")
+		for _, item := range result {
+			if item.Err != nil {
+				console.PrintF("@BUb{%s} @r{FAILED:} @I{%s}\n", item.Tenant, item.Err.Error())
+			} else {
+				console.PrintF("@BUb{%s} @g{%d files}\n", item.Tenant, len(item.Result))
+			}
+
+			for _, file := range item.Result {
+				console.PrintF("    @g{%s:} @I{%s} (%s)\n", label, file.Name, strings.ToLower(file.Stage))
+			}
+		}
+		fmt.Println()
+	})
+}
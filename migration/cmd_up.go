@@ -1,6 +1,7 @@
 package migration
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -12,8 +13,12 @@ func cmdUp(m Migration, option *cliOption) *cobra.Command {
 	upCmd := &cobra.Command{}
 	upCmd.Use = "up [stage1, stage2, ...]"
 	upCmd.Short = "applies migrations"
-	upCmd.Flags().StringP("name", "n", "", "migration name")
-	upCmd.Run = func(cmd *cobra.Command, args []string) {
+	upCmd.Flags().StringP("name", "n", "", "migration name, directory qualified name or glob")
+	upCmd.Flags().Int("steps", 0, "number of files to apply")
+	upCmd.Flags().Int64("to", 0, "apply files up to the timestamp")
+	upCmd.Flags().Bool("dry-run", false, "print scripts without running them")
+	addTenantFlags(upCmd, option)
+	upCmd.RunE = func(cmd *cobra.Command, args []string) error {
 		if option.callback != nil {
 			defer option.callback()
 		}
@@ -24,27 +29,55 @@ func cmdUp(m Migration, option *cliOption) *cobra.Command {
 		}
 
 		if len(stages) == 0 {
-			console.Message().
-				Red("Up").Italic().
-				Print("no stage stage specified")
-			return
+			return report(cmd, "Up", nil, errors.New("no stage stage specified"), nil)
 		}
 
 		options := make([]MigrationOption, 0)
 		if name := getFlag(cmd, "name"); name != "" {
 			options = append(options, OnlyFiles(name))
 		}
+		if steps := getIntFlag(cmd, "steps"); steps > 0 {
+			options = append(options, Steps(steps))
+		}
+		if cmd.Flags().Changed("to") {
+			to, _ := cmd.Flags().GetInt64("to")
+			options = append(options, Target(to))
+		}
+
+		if tenants, ok := tenantFlags(cmd, option); ok {
+			if getBoolFlag(cmd, "dry-run") {
+				return report(cmd, "Up", nil, errTenantDryRun, nil)
+			}
+
+			result, err := option.tenants.Up(cmd.Context(), tenants, stages, options...)
+			return reportTenants(cmd, "Up", "UP", result, err)
+		}
+
+		if getBoolFlag(cmd, "dry-run") {
+			plan := make(Plan, 0)
+			options = append(options, DryRun(&plan))
+			if _, err := m.UpContext(cmd.Context(), stages, options...); err != nil {
+				return report(cmd, "Up", nil, err, nil)
+			}
+
+			return report(cmd, "Up", plan, nil, func() { printPlan(plan) })
+		}
 
-		result, err := m.Up(stages, options...)
-		if err != nil {
-			console.Message().Red("Up").Italic().Print(err.Error())
-			return
+		result, err := m.UpContext(cmd.Context(), stages, options...)
+		if result == nil {
+			result = make(Summary, 0)
+		}
+		if err != nil && result.IsEmpty() {
+			return report(cmd, "Up", result, err, nil)
 		}
 
-		console.PrintF("@Bwb{ Migrate Summery: }\n")
-		if result.IsEmpty() {
-			console.Message().Indent().Italic().Print("nothing to migrate")
-		} else {
+		return report(cmd, "Up", result, err, func() {
+			console.PrintF("@Bwb{ Migrate Summery: }\n")
+			if result.IsEmpty() {
+				console.Message().Indent().Italic().Print("nothing to migrate")
+				return
+			}
+
 			for stage, files := range result.GroupByStage() {
 				console.PrintF("@BUb{%s} @b{Stage} @Ib{(%d Files)}:\n", strings.ToTitle(stage), len(files))
 				for _, file := range files {
@@ -53,7 +86,7 @@ func cmdUp(m Migration, option *cliOption) *cobra.Command {
 
 				fmt.Println()
 			}
-		}
+		})
 	}
 
 	return upCmd
@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/go-universal/console"
+	"github.com/spf13/cobra"
+)
+
+func cmdVerify(m Migration, option *cliOption) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "detect applied migrations changed after being applied",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if option.callback != nil {
+				defer option.callback()
+			}
+
+			drifts, err := m.VerifyContext(cmd.Context())
+			if err != nil {
+				return report(cmd, "Verify", nil, err, nil)
+			}
+
+			return report(cmd, "Verify", drifts, nil, func() {
+				if len(drifts) == 0 {
+					console.Message().Green("Verify").Italic().Print("applied migrations match files")
+					return
+				}
+
+				console.PrintF("@Bwb{ Drift Summery: }\n")
+				groups := make(map[string][]Drift)
+				for _, drift := range drifts {
+					groups[drift.Stage] = append(groups[drift.Stage], drift)
+				}
+				for stage, files := range groups {
+					console.PrintF("@BUb{%s} @b{Stage} @Ib{(%d Files)}:\n", strings.ToTitle(stage), len(files))
+					for _, file := range files {
+						switch file.State {
+						case DriftRemoved:
+							console.PrintF("    @r{REMOVED:} @I{%s} @I{(applied %s)}\n", file.Name, humanize.Time(file.Migrated))
+						case DriftUnknown:
+							console.PrintF("    @p{UNKNOWN:} @I{%s} @I{(applied %s without checksum)}\n", file.Name, humanize.Time(file.Migrated))
+						default:
+							console.PrintF("    @y{CHANGED:} @I{%s} @I{(applied %s)}\n", file.Name, humanize.Time(file.Migrated))
+						}
+					}
+
+					fmt.Println()
+				}
+			})
+		},
+	}
+}
@@ -13,6 +13,9 @@ func (fs sortableFiles) Swap(i, j int) {
 }
 
 func (fs sortableFiles) Less(i, j int) bool {
+	if fs[i].timestamp == fs[j].timestamp {
+		return fs[i].name < fs[j].name
+	}
 	return fs[i].timestamp < fs[j].timestamp
 }
 
@@ -26,11 +29,13 @@ func (fs sortableFiles) Reverse() sortableFiles {
 	return result
 }
 
+// Filter returns files matching only patterns and not matching exclude patterns.
+// Patterns are names, directory qualified names or globs (e.g. "clients/*").
 func (fs sortableFiles) Filter(only, exclude []string) sortableFiles {
-	skip := func(name string) bool {
-		if name == "" ||
-			(len(only) > 0 && !slices.Contains(only, name)) ||
-			(len(exclude) > 0 && slices.Contains(exclude, name)) {
+	skip := func(file migrationFile) bool {
+		if file.name == "" ||
+			(len(only) > 0 && !file.Match(only...)) ||
+			(len(exclude) > 0 && file.Match(exclude...)) {
 			return true
 		}
 
@@ -39,7 +44,38 @@ func (fs sortableFiles) Filter(only, exclude []string) sortableFiles {
 
 	result := make(sortableFiles, 0)
 	for _, file := range fs {
-		if !skip(file.name) {
+		if !skip(file) {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+func (fs sortableFiles) Find(name string) (migrationFile, bool) {
+	for _, file := range fs {
+		if file.name == name {
+			return file, true
+		}
+	}
+	return migrationFile{}, false
+}
+
+// Until returns the files with timestamp less than or equal to the timestamp.
+func (fs sortableFiles) Until(timestamp int64) sortableFiles {
+	result := make(sortableFiles, 0)
+	for _, file := range fs {
+		if file.timestamp <= timestamp {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// After returns the files with timestamp greater than the timestamp.
+func (fs sortableFiles) After(timestamp int64) sortableFiles {
+	result := make(sortableFiles, 0)
+	for _, file := range fs {
+		if file.timestamp > timestamp {
 			result = append(result, file)
 		}
 	}
@@ -2,8 +2,11 @@ package migration
 
 import (
 	"bufio"
+	"fmt"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -11,26 +14,80 @@ import (
 type migrationFile struct {
 	timestamp   int64
 	name        string
+	legacy      string
 	extension   string
+	stages      []string
+	options     []string
+	allowed     []string
+	squashes    []string
 	upScripts   map[string]string
 	downScripts map[string]string
+	upLines     map[string][]int
+	downLines   map[string][]int
+	repeats     []string
+	repeatables map[string]string
+	repeatLines map[string][]int
+	upFuncs     map[string]MigrationFunc
+	downFuncs   map[string]MigrationFunc
 }
 
 // newMigrationFile parses the migration file's path and content, extracting metadata and SQL scripts.
 func newMigrationFile(path, content string) *migrationFile {
 	// Extract file details and SQL scripts for "up" and "down" stages.
 	timestamp, name, ext, ok := parseFileName(filepath.Base(path))
+	repeatable := false
 	if !ok {
-		return nil
+		if name, ext, ok = parseRepeatableFileName(filepath.Base(path)); !ok {
+			return nil
+		}
+		repeatable = true
 	}
 
-	return &migrationFile{
+	upScripts, upLines := parseSections(content, "up")
+	downScripts, downLines := parseSections(content, "down")
+	repeatables, repeatLines := parseSections(content, "repeatable")
+	file := &migrationFile{
 		timestamp:   timestamp,
 		name:        name,
+		legacy:      name,
 		extension:   ext,
-		upScripts:   parseFileSections(content, "up"),
-		downScripts: parseFileSections(content, "down"),
+		stages:      parseSectionNames(content, "up"),
+		options:     parseFileOptions(content),
+		allowed:     parseDirective(content, "allow"),
+		squashes:    parseDirectiveValues(content, "squashes"),
+		upScripts:   upScripts,
+		downScripts: downScripts,
+		upLines:     upLines,
+		downLines:   downLines,
+		repeats:     parseSectionNames(content, "repeatable"),
+		repeatables: repeatables,
+		repeatLines: repeatLines,
+	}
+
+	// Up sections of repeatable files are repeatable
+	if repeatable {
+		for _, stage := range file.stages {
+			if !slices.Contains(file.repeats, stage) {
+				file.repeats = append(file.repeats, stage)
+				file.repeatables[stage] = upScripts[stage]
+				file.repeatLines[stage] = upLines[stage]
+			}
+		}
+		file.stages = make([]string, 0)
+		file.upScripts = make(map[string]string)
+		file.upLines = make(map[string][]int)
 	}
+	return file
+}
+
+// validate checks the file doesn't define a stage as both up and repeatable.
+func (f migrationFile) validate() error {
+	for _, stage := range f.repeats {
+		if slices.Contains(f.stages, stage) {
+			return fmt.Errorf(`"%s" defines %s stage as both up and repeatable`, f.name, stage)
+		}
+	}
+	return nil
 }
 
 // UpScript retrieves the "up" script for a specific stage.
@@ -45,6 +102,122 @@ func (f migrationFile) DownScript(stage string) (string, bool) {
 	return v, ok
 }
 
+// Match reports whether the file name, or its name without directory, matches any of the patterns.
+func (f migrationFile) Match(patterns ...string) bool {
+	for _, pattern := range patterns {
+		for _, name := range []string{f.name, f.legacy} {
+			if name == pattern {
+				return true
+			}
+
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsFunc reports whether the file is a registered Go migration.
+func (f migrationFile) IsFunc() bool {
+	return f.extension == funcExtension
+}
+
+// UpStep builds the "up" step for a specific stage.
+// Returns false if the stage has nothing to apply.
+func (f migrationFile) UpStep(stage string) (Step, bool) {
+	step := Step{
+		Name:          f.name,
+		Stage:         stage,
+		Direction:     DirectionUp,
+		NoTransaction: !f.Transactional(),
+		timestamp:     f.timestamp,
+	}
+
+	if fn := f.upFuncs[stage]; fn != nil {
+		step.fn = fn
+		return step, true
+	}
+
+	script, ok := f.UpScript(stage)
+	if !ok || len(script) == 0 {
+		return step, false
+	}
+
+	step.Script = script
+	step.lines = f.upLines[stage]
+	return step, true
+}
+
+// DownStep builds the "down" step for a specific stage.
+// Returns false if the stage has no rollback section.
+func (f migrationFile) DownStep(stage string) (Step, bool) {
+	step := Step{
+		Name:          f.name,
+		Stage:         stage,
+		Direction:     DirectionDown,
+		NoTransaction: !f.Transactional(),
+		timestamp:     f.timestamp,
+	}
+
+	if fn := f.downFuncs[stage]; fn != nil {
+		step.fn = fn
+		return step, true
+	}
+
+	script, ok := f.DownScript(stage)
+	if !ok {
+		return step, false
+	}
+
+	step.Script = script
+	step.lines = f.downLines[stage]
+	return step, true
+}
+
+// Stages returns the stages defined by the file's "up" sections in order of appearance.
+func (f migrationFile) Stages() []string {
+	return append([]string{}, f.stages...)
+}
+
+// RepeatableStages returns the stages defined by the file's "repeatable" sections in order of appearance.
+func (f migrationFile) RepeatableStages() []string {
+	return append([]string{}, f.repeats...)
+}
+
+// RepeatableStep builds the repeatable step for a specific stage.
+// Returns false if the stage has no repeatable script.
+func (f migrationFile) RepeatableStep(stage string) (Step, bool) {
+	script, ok := f.repeatables[stage]
+	if !ok || len(script) == 0 {
+		return Step{}, false
+	}
+
+	return Step{
+		Name:          f.name,
+		Stage:         stage,
+		Direction:     DirectionUp,
+		Script:        script,
+		NoTransaction: !f.Transactional(),
+		Repeatable:    true,
+		timestamp:     f.timestamp,
+		lines:         f.repeatLines[stage],
+	}, true
+}
+
+// Transactional reports whether the file scripts run inside a transaction.
+// Files with the "-- { options: no-transaction }" directive run outside of transactions.
+func (f migrationFile) Transactional() bool {
+	return !slices.Contains(f.options, "no-transaction")
+}
+
+// IsBaseline reports whether the file is a baseline of squashed files.
+// Files with the "-- { options: baseline }" directive are baselines,
+// the "-- { squashes: name, ... }" directive lists the names of the squashed files.
+func (f migrationFile) IsBaseline() bool {
+	return slices.Contains(f.options, "baseline")
+}
+
 // parseFileName extracts the timestamp, name, and extension from a file name.
 // Returns the extracted values and true if successful, or zero values and false on failure.
 func parseFileName(name string) (int64, string, string, bool) {
@@ -68,50 +241,127 @@ func parseFileName(name string) (int64, string, string, bool) {
 	return timestamp, strings.ReplaceAll(matches[2], "-", " "), matches[3], true
 }
 
-// parseFileSections extracts SQL sections defined by the format "-- {section: name}".
-func parseFileSections(content, section string) map[string]string {
-	var name, body string
-	res := make(map[string]string)
+// parseRepeatableFileName extracts the name and extension from a "R-name.ext" repeatable file name.
+func parseRepeatableFileName(name string) (string, string, bool) {
+	matches := repeatableFileName.FindStringSubmatch(name)
+	if len(matches) != 3 {
+		return "", "", false
+	}
+	return strings.ReplaceAll(matches[1], "-", " "), matches[2], true
+}
+
+// repeatableFileName matches repeatable file names without timestamp.
+var repeatableFileName = regexp.MustCompile(`^R-([a-zA-Z0-9-]+)\.([a-zA-Z0-9]+)$`)
 
-	// Regex to match section tags.
-	rx := regexp.MustCompile(`^\s*--\s*\{\s*(\w+):\s*([\w\s]+)\s*\}$`)
-	parseTag := func(content string) (string, string, bool) {
-		matches := rx.FindStringSubmatch(content)
-		if len(matches) == 3 {
-			matches[2] = strings.TrimSpace(matches[2])
-			if matches[2] != "" {
-				return matches[1], matches[2], true
+// sectionTag matches section tags in the format "-- {section: name}".
+var sectionTag = regexp.MustCompile(`^\s*--\s*\{\s*(\w+):\s*([\w\s,/-]+)\s*\}$`)
+
+// directiveTags lists file level tags that neither start nor end script sections.
+var directiveTags = []string{"options", "allow", "squashes"}
+
+// parseTag extracts the section and name from a section tag line.
+func parseTag(line string) (string, string, bool) {
+	matches := sectionTag.FindStringSubmatch(line)
+	if len(matches) == 3 {
+		matches[2] = strings.TrimSpace(matches[2])
+		if matches[2] != "" {
+			return matches[1], matches[2], true
+		}
+	}
+	return "", "", false
+}
+
+// parseSectionNames extracts the names of a section type in order of appearance.
+func parseSectionNames(content, section string) []string {
+	res := make([]string, 0)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		tag, name, ok := parseTag(strings.TrimSpace(scanner.Text()))
+		if ok && tag == section && !slices.Contains(res, name) {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// parseFileOptions extracts the comma separated values of "-- { options: a, b }" directives.
+func parseFileOptions(content string) []string {
+	return parseDirective(content, "options")
+}
+
+// parseDirective extracts the lower cased comma separated values of "-- { tag: a, b }" directives.
+func parseDirective(content, tag string) []string {
+	res := make([]string, 0)
+	for _, value := range parseDirectiveValues(content, tag) {
+		value = strings.ToLower(value)
+		if !slices.Contains(res, value) {
+			res = append(res, value)
+		}
+	}
+	return res
+}
+
+// parseDirectiveValues extracts the comma separated values of "-- { tag: a, b }" directives as written.
+func parseDirectiveValues(content, tag string) []string {
+	res := make([]string, 0)
+	for _, value := range parseSectionNames(content, tag) {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" && !slices.Contains(res, item) {
+				res = append(res, item)
 			}
 		}
-		return "", "", false
 	}
+	return res
+}
+
+// parseFileSections extracts SQL sections defined by the format "-- {section: name}".
+func parseFileSections(content, section string) map[string]string {
+	res, _ := parseSections(content, section)
+	return res
+}
+
+// parseSections extracts SQL sections and the file line number of each section line.
+func parseSections(content, section string) (map[string]string, map[string][]int) {
+	var name, body string
+	var lines []int
+	res := make(map[string]string)
+	resLines := make(map[string][]int)
 
 	// Scan and parse the content line by line.
+	number := 0
 	scanner := bufio.NewScanner(strings.NewReader(content))
 	for scanner.Scan() {
+		number++
 		line := strings.TrimSpace(scanner.Text())
 		tag, query, isNew := parseTag(line)
-		if isNew {
+		if isNew && slices.Contains(directiveTags, tag) {
+			continue
+		} else if isNew {
 			// Save previous section if it exists.
 			if name != "" {
 				res[name] = strings.TrimRight(body, "\n")
+				resLines[name] = lines
 			}
 
 			// Start a new section.
 			name = ""
 			body = ""
+			lines = nil
 			if tag == section {
 				name = query
 			}
 		} else if line != "" && name != "" {
 			body = body + line + "\n"
+			lines = append(lines, number)
 		}
 	}
 
 	// Save the last section.
 	if name != "" {
 		res[name] = strings.TrimRight(body, "\n")
+		resLines[name] = lines
 	}
 
-	return res
+	return res, resLines
 }
@@ -0,0 +1,325 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FileFormat parses migration files of another migration tool layout as single stage migrations.
+type FileFormat interface {
+	// Parse parses the migration file at path.
+	// Returns false if the file doesn't belong to the format,
+	// or an error if the file belongs to the format but can't be imported.
+	Parse(path, content string) (ParsedFile, bool, error)
+
+	// Applied reads the tool's history table and returns the given versions applied by the tool.
+	Applied(ctx context.Context, db ExecutableScanner, versions []int64) ([]int64, error)
+}
+
+// ParsedFile is a migration, or a part of it, parsed by a FileFormat.
+// Parts with the same timestamp and name (e.g. separate up and down files) are merged.
+type ParsedFile struct {
+	Timestamp int64
+	Name      string
+	Up        map[string]string // Up scripts by stage.
+	Down      map[string]string // Down scripts by stage.
+	Options   []string          // File options, e.g. "no-transaction".
+}
+
+// GolangMigrateFormat reads golang-migrate layouts ("0001_name.up.sql" and "0001_name.down.sql")
+// into the stage. Applied versions are read from the "schema_migrations" table.
+func GolangMigrateFormat(stage string) FileFormat {
+	return &golangMigrateFormat{stage: stage}
+}
+
+type golangMigrateFormat struct {
+	stage string
+}
+
+var golangMigrateRx = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.[a-zA-Z0-9]+$`)
+
+func (f *golangMigrateFormat) Parse(path, content string) (ParsedFile, bool, error) {
+	matches := golangMigrateRx.FindStringSubmatch(filepath.Base(path))
+	if len(matches) != 4 {
+		return ParsedFile{}, false, nil
+	}
+
+	timestamp, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return ParsedFile{}, false, fmt.Errorf("%s: invalid version: %w", path, err)
+	}
+
+	result := newParsedFile(timestamp, matches[2])
+	if matches[3] == "up" {
+		result.Up[f.stage] = normalizeScript(content)
+	} else {
+		result.Down[f.stage] = normalizeScript(content)
+	}
+	return result, true, nil
+}
+
+func (f *golangMigrateFormat) Applied(ctx context.Context, db ExecutableScanner, versions []int64) ([]int64, error) {
+	rows, err := db.Scan(ctx, `SELECT version, dirty FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Versions up to the recorded one are applied, except the dirty one
+	var current int64 = -1
+	for rows.Next() {
+		var version int64
+		var dirty bool
+		if err := rows.Scan(&version, &dirty); err != nil {
+			return nil, err
+		}
+
+		if dirty {
+			version--
+		}
+		current = max(current, version)
+	}
+	if err := rowsErr(rows); err != nil {
+		return nil, err
+	}
+
+	result := make([]int64, 0)
+	for _, version := range versions {
+		if version <= current {
+			result = append(result, version)
+		}
+	}
+	return result, nil
+}
+
+// GooseFormat reads goose layouts ("00001_name.sql" with "-- +goose Up" and "-- +goose Down" annotations)
+// into the stage. Applied versions are read from the "goose_db_version" table.
+func GooseFormat(stage string) FileFormat {
+	return &gooseFormat{stage: stage}
+}
+
+type gooseFormat struct {
+	stage string
+}
+
+var gooseRx = regexp.MustCompile(`^(\d+)_(.+)\.sql$`)
+
+func (f *gooseFormat) Parse(path, content string) (ParsedFile, bool, error) {
+	matches := gooseRx.FindStringSubmatch(filepath.Base(path))
+	if len(matches) != 3 {
+		return ParsedFile{}, false, nil
+	}
+
+	timestamp, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return ParsedFile{}, false, fmt.Errorf("%s: invalid version: %w", path, err)
+	}
+
+	// Split annotated sections
+	var section string
+	up := make([]string, 0)
+	down := make([]string, 0)
+	result := newParsedFile(timestamp, matches[2])
+	for _, line := range strings.Split(content, "\n") {
+		annotation := strings.ToLower(strings.Join(strings.Fields(line), " "))
+		switch annotation {
+		case "-- +goose up":
+			section = "up"
+		case "-- +goose down":
+			section = "down"
+		case "-- +goose no transaction":
+			result.Options = append(result.Options, "no-transaction")
+		case "-- +goose statementbegin", "-- +goose statementend":
+		default:
+			if section == "up" {
+				up = append(up, line)
+			} else if section == "down" {
+				down = append(down, line)
+			}
+		}
+	}
+
+	if section == "" {
+		return ParsedFile{}, false, nil
+	}
+
+	result.Up[f.stage] = normalizeScript(strings.Join(up, "\n"))
+	if len(down) > 0 {
+		result.Down[f.stage] = normalizeScript(strings.Join(down, "\n"))
+	}
+	return result, true, nil
+}
+
+func (f *gooseFormat) Applied(ctx context.Context, db ExecutableScanner, versions []int64) ([]int64, error) {
+	rows, err := db.Scan(ctx, `SELECT version_id, is_applied FROM goose_db_version ORDER BY id ASC;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Latest row of each version wins
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		if err := rows.Scan(&version, &isApplied); err != nil {
+			return nil, err
+		}
+		applied[version] = isApplied
+	}
+	if err := rowsErr(rows); err != nil {
+		return nil, err
+	}
+	return appliedVersions(versions, applied), nil
+}
+
+// FlywayFormat reads Flyway layouts ("V1__name.sql" and optional "U1__name.sql" undo files)
+// with integer versions into the stage. Applied versions are read from the "flyway_schema_history" table.
+// Files with dotted versions (e.g. "V1.1__name.sql") can't be mapped to timestamps and fail to parse.
+func FlywayFormat(stage string) FileFormat {
+	return &flywayFormat{stage: stage}
+}
+
+type flywayFormat struct {
+	stage string
+}
+
+var flywayRx = regexp.MustCompile(`^([VU])(\d+(?:[._]\d+)*)__(.+)\.sql$`)
+
+func (f *flywayFormat) Parse(path, content string) (ParsedFile, bool, error) {
+	matches := flywayRx.FindStringSubmatch(filepath.Base(path))
+	if len(matches) != 4 {
+		return ParsedFile{}, false, nil
+	}
+
+	timestamp, err := strconv.ParseInt(matches[2], 10, 64)
+	if err != nil {
+		return ParsedFile{}, false, fmt.Errorf("%s: unsupported version %q, only integer versions can be imported", path, matches[2])
+	}
+
+	result := newParsedFile(timestamp, matches[3])
+	if matches[1] == "V" {
+		result.Up[f.stage] = normalizeScript(content)
+	} else {
+		result.Down[f.stage] = normalizeScript(content)
+	}
+	return result, true, nil
+}
+
+func (f *flywayFormat) Applied(ctx context.Context, db ExecutableScanner, versions []int64) ([]int64, error) {
+	rows, err := db.Scan(ctx, `SELECT version FROM flyway_schema_history WHERE success AND version IS NOT NULL;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+
+		v, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported applied version %q, only integer versions can be imported", version)
+		}
+		applied[v] = true
+	}
+	if err := rowsErr(rows); err != nil {
+		return nil, err
+	}
+	return appliedVersions(versions, applied), nil
+}
+
+// appliedVersions returns the versions marked as applied.
+func appliedVersions(versions []int64, applied map[int64]bool) []int64 {
+	result := make([]int64, 0)
+	for _, version := range versions {
+		if applied[version] {
+			result = append(result, version)
+		}
+	}
+	return result
+}
+
+// convertedFileName returns the native file name of a converted migration.
+func convertedFileName(timestamp int64, name, ext string) string {
+	return fmt.Sprintf("%d-%s.%s", timestamp, slugify(name), ext)
+}
+
+// newParsedFile creates a parsed file with name normalized to the native format.
+func newParsedFile(timestamp int64, name string) ParsedFile {
+	return ParsedFile{
+		Timestamp: timestamp,
+		Name:      strings.Join(strings.FieldsFunc(name, isNameSeparator), " "),
+		Up:        make(map[string]string),
+		Down:      make(map[string]string),
+		Options:   make([]string, 0),
+	}
+}
+
+// isNameSeparator reports whether r separates words in migration names.
+func isNameSeparator(r rune) bool {
+	return r == '_' || r == '-' || r == ' '
+}
+
+// normalizeScript trims lines and removes empty lines like native file sections.
+func normalizeScript(content string) string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// merge adds the parsed file to the files, merging with an existing file of the same timestamp and name.
+func (fs sortableFiles) merge(parsed ParsedFile, name string) sortableFiles {
+	for i, file := range fs {
+		if file.timestamp != parsed.Timestamp || file.name != name {
+			continue
+		}
+
+		for stage, script := range parsed.Up {
+			if !slices.Contains(fs[i].stages, stage) {
+				fs[i].stages = append(fs[i].stages, stage)
+			}
+			fs[i].upScripts[stage] = script
+		}
+		for stage, script := range parsed.Down {
+			fs[i].downScripts[stage] = script
+		}
+		for _, option := range parsed.Options {
+			if !slices.Contains(fs[i].options, option) {
+				fs[i].options = append(fs[i].options, option)
+			}
+		}
+		return fs
+	}
+
+	file := migrationFile{
+		timestamp:   parsed.Timestamp,
+		name:        name,
+		legacy:      parsed.Name,
+		extension:   "sql",
+		stages:      make([]string, 0),
+		options:     append([]string{}, parsed.Options...),
+		upScripts:   make(map[string]string),
+		downScripts: make(map[string]string),
+	}
+	for stage, script := range parsed.Up {
+		file.stages = append(file.stages, stage)
+		file.upScripts[stage] = script
+	}
+	for stage, script := range parsed.Down {
+		file.downScripts[stage] = script
+	}
+	return append(fs, file)
+}
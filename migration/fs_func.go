@@ -0,0 +1,64 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// funcExtension is the extension reported by registered Go migrations.
+const funcExtension = "go"
+
+// MigrationFunc runs a Go migration step.
+// The db is the running transaction, or the source for non-transactional runs.
+type MigrationFunc func(ctx context.Context, db ExecutableScanner) error
+
+// register adds the stage functions to the Go migration with the timestamp and name,
+// creating the migration if it isn't registered yet.
+func (fs sortableFiles) register(timestamp int64, name, stage string, up, down MigrationFunc) (sortableFiles, error) {
+	name = strings.TrimSpace(name)
+	stage = strings.TrimSpace(stage)
+	if name == "" || stage == "" {
+		return nil, errors.New("name and stage parameters are required")
+	}
+
+	if up == nil {
+		return nil, fmt.Errorf(`"%s" up function is required`, name)
+	}
+
+	for i, file := range fs {
+		if file.timestamp != timestamp || file.name != name {
+			continue
+		}
+
+		if slices.Contains(file.stages, stage) {
+			return nil, fmt.Errorf(`"%s" already registered for %s stage`, name, stage)
+		}
+
+		fs[i].stages = append(file.stages, stage)
+		fs[i].upFuncs[stage] = up
+		if down != nil {
+			fs[i].downFuncs[stage] = down
+		}
+		return fs, nil
+	}
+
+	file := migrationFile{
+		timestamp:   timestamp,
+		name:        name,
+		legacy:      name,
+		extension:   funcExtension,
+		stages:      []string{stage},
+		options:     make([]string, 0),
+		upScripts:   make(map[string]string),
+		downScripts: make(map[string]string),
+		upFuncs:     map[string]MigrationFunc{stage: up},
+		downFuncs:   make(map[string]MigrationFunc),
+	}
+	if down != nil {
+		file.downFuncs[stage] = down
+	}
+	return append(fs, file), nil
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/go-universal/fs"
 )
 
 // CreateMigrationFile creates a migration file in the specified root directory with the given name, extension, and optional stages.
@@ -17,23 +20,86 @@ func CreateMigrationFile(root, name, ext string, stages ...string) error {
 		return errors.New("root, name, and extension parameters are required")
 	}
 
-	// Normalize the path and generate the file name.
+	// Prepare empty sections with the provided stages, defaulting to "main".
+	if len(stages) == 0 {
+		stages = []string{"main"}
+	}
+	sections := make(map[string]string)
+	for _, stage := range stages {
+		sections[stage] = ""
+	}
+
+	return writeMigrationFile(root, generateFileName(name, ext), stages, sections, sections, nil, nil)
+}
+
+// ConvertMigrationFiles reads migration files of the format from dir of src and writes them
+// to root in the "-- { up: stage }" format with the given extension.
+// Returns the names of written files.
+func ConvertMigrationFiles(src fs.FlexibleFS, dir string, format FileFormat, root, ext string) ([]string, error) {
+	if root == "" || ext == "" || format == nil {
+		return nil, errors.New("root, extension, and format parameters are required")
+	}
+
+	paths, err := src.Lookup(dir, `.*`)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse and merge files
+	files := make(sortableFiles, 0)
+	for _, path := range paths {
+		content, err := src.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, ok, err := format.Parse(path, string(content))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			files = files.merge(parsed, parsed.Name)
+		}
+	}
+	sort.Sort(files)
+
+	// Write native files
+	result := make([]string, 0, len(files))
+	for _, file := range files {
+		name := convertedFileName(file.timestamp, file.name, ext)
+		err := writeMigrationFile(root, name, file.stages, file.upScripts, file.downScripts, file.options, nil)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+// writeMigrationFile writes the up and down sections of stages to the file in the root directory.
+// Stages without down script are written without down section, squashes lists the files replaced by a baseline.
+func writeMigrationFile(root, name string, stages []string, up, down map[string]string, options, squashes []string) error {
+	// Normalize the path.
 	root = normalizePath(root)
-	name = generateFileName(name, ext)
 
 	// Create the directory if it doesn't exist.
 	if err := os.MkdirAll(root, os.ModeDir|0755); err != nil {
 		return err
 	}
 
-	// Prepare content with the provided stages, defaulting to "main".
+	// Prepare content.
 	content := make([]string, 0)
-	if len(stages) == 0 {
-		stages = []string{"main"}
+	if len(options) > 0 {
+		content = append(content, fmt.Sprintf("-- { options: %s }\n\n", strings.Join(options, ", ")))
+	}
+	if len(squashes) > 0 {
+		content = append(content, fmt.Sprintf("-- { squashes: %s }\n\n", strings.Join(squashes, ", ")))
 	}
 	for _, stage := range stages {
-		content = append(content, fmt.Sprintf("-- { up: %s }\n\n", stage))
-		content = append(content, fmt.Sprintf("-- { down: %s }\n\n", stage))
+		content = append(content, fmt.Sprintf("-- { up: %s }\n%s", stage, sectionBody(up[stage])))
+		if script, ok := down[stage]; ok {
+			content = append(content, fmt.Sprintf("-- { down: %s }\n%s", stage, sectionBody(script)))
+		}
 	}
 
 	// Write the generated content to the file.
@@ -47,6 +113,14 @@ func CreateMigrationFile(root, name, ext string, stages ...string) error {
 	return nil
 }
 
+// sectionBody formats a section script followed by a blank line.
+func sectionBody(script string) string {
+	if script == "" {
+		return "\n"
+	}
+	return script + "\n\n"
+}
+
 // generateFileName generates a migration file name with a Unix timestamp, slugified name, and specified extension.
 func generateFileName(name, ext string) string {
 	return fmt.Sprintf(
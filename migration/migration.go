@@ -2,9 +2,12 @@ package migration
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,41 +30,177 @@ type Migration interface {
 	// IsDev indicates if it is in development mode.
 	IsDev() bool
 
-	// Initialize sets up the database migration table.
+	// Register adds a Go function migration for a stage, sorted with migration files by timestamp
+	// and tracked in the migrations table. The down function is optional.
+	Register(timestamp int64, name, stage string, up, down MigrationFunc) error
+
+	// Initialize sets up the database migration table with a 10 second timeout.
 	Initialize() error
 
-	// Summary returns an overview of the migration.
+	// InitializeContext sets up the database migration table using the context.
+	InitializeContext(ctx context.Context) error
+
+	// Summary returns an overview of the migration with a 10 second timeout.
 	Summary() (Summary, error)
 
-	// Up applies migration stages.
+	// SummaryContext returns an overview of the migration using the context.
+	SummaryContext(ctx context.Context) (Summary, error)
+
+	// Up applies migration stages with a 300 second timeout.
+	// On failure, the entries committed before the failure are returned with a *MigrationError.
 	Up(stages []string, options ...MigrationOption) (Summary, error)
 
-	// Down rolls back migration stages.
+	// UpContext applies migration stages using the context.
+	UpContext(ctx context.Context, stages []string, options ...MigrationOption) (Summary, error)
+
+	// Down rolls back migration stages with a 300 second timeout.
+	// On failure, the entries committed before the failure are returned with a *MigrationError.
 	Down(stages []string, options ...MigrationOption) (Summary, error)
 
-	// Refresh rolls back and reapplies migration stages.
+	// DownContext rolls back migration stages using the context.
+	DownContext(ctx context.Context, stages []string, options ...MigrationOption) (Summary, error)
+
+	// Refresh rolls back and reapplies migration stages with a 300 second timeout.
+	// On failure, the entries committed before the failure are returned with a *MigrationError.
 	Refresh(stages []string, options ...MigrationOption) (Summary, error)
+
+	// RefreshContext rolls back and reapplies migration stages using the context.
+	RefreshContext(ctx context.Context, stages []string, options ...MigrationOption) (Summary, error)
+
+	// Status compares migration files with the migrations table
+	// and returns applied, pending and orphaned entries per stage.
+	Status() (StatusReport, error)
+
+	// StatusContext is like Status but uses the context.
+	StatusContext(ctx context.Context) (StatusReport, error)
+
+	// Verify compares applied migrations with current file contents
+	// and returns entries whose up script changed or was removed after being applied.
+	// Entries applied before checksums were tracked are reported with the unknown state.
+	Verify() ([]Drift, error)
+
+	// VerifyContext is like Verify but uses the context.
+	VerifyContext(ctx context.Context) ([]Drift, error)
+
+	// Import records migrations applied by another tool, read from its history table, as applied
+	// and returns the recorded entries. Migrations are matched by version with file timestamps.
+	// Only the files converted by ConvertMigrationFiles (their returned names) are imported,
+	// converted files missing from the migration root fail the import.
+	// Files can be omitted when the migration reads the format itself (WithFormat).
+	Import(format FileFormat, files ...string) (Summary, error)
+
+	// ImportContext is like Import but uses the context.
+	ImportContext(ctx context.Context, format FileFormat, files ...string) (Summary, error)
+
+	// Lint checks migration files for ignored file names, duplicate timestamps, empty up sections,
+	// missing down sections and pending files older than the latest applied one with a 10 second timeout.
+	// Stages outside of the given stages are reported when stages are passed. The migration has no
+	// default stages, so with no stages the unknown-stage rule is skipped: pass the expected stages
+	// (the lint command passes the WithDefaultStages ones) to enforce it.
+	Lint(stages ...string) ([]LintIssue, error)
+
+	// LintContext is like Lint but uses the context.
+	LintContext(ctx context.Context, stages ...string) ([]LintIssue, error)
+
+	// Analyze inspects up scripts of the stages (all stages if empty) for statements taking long
+	// locks on Postgres and returns them with safer alternatives.
+	// Rules are suppressed per file by the "-- { allow: create-index, column-type }" (or "all") directive.
+	// Scripts are analyzed as rendered by WithTemplateData, with a 10 second timeout.
+	// Returns ErrUnsupportedDialect for other sources.
+	Analyze(stages ...string) ([]Finding, error)
+
+	// AnalyzeContext is like Analyze but uses the context.
+	AnalyzeContext(ctx context.Context, stages ...string) ([]Finding, error)
+
+	// CheckReversible runs pending files of the stages up, down and up again inside a rollback-only transaction
+	// and reports schema differences between before up and after down, using information_schema,
+	// with a 300 second timeout. MySQL commits DDL statements implicitly, so it returns ErrUnsupportedDialect
+	// on sources other than Postgres unless a scratch database is passed with the Scratch option.
+	CheckReversible(stages []string, options ...MigrationOption) ([]ReversibilityReport, error)
+
+	// CheckReversibleContext is like CheckReversible but uses the context.
+	CheckReversibleContext(ctx context.Context, stages []string, options ...MigrationOption) ([]ReversibilityReport, error)
+
+	// Mark records pending migration stages as applied without running their scripts,
+	// e.g. when adopting an existing database, with a 10 second timeout.
+	// OnlyFiles, SkipFiles, Steps, Target and DryRun options are respected.
+	Mark(stages []string, options ...MigrationOption) (Summary, error)
+
+	// MarkContext is like Mark but uses the context.
+	MarkContext(ctx context.Context, stages []string, options ...MigrationOption) (Summary, error)
+
+	// Unmark removes applied entries of the stages without running their down scripts,
+	// including entries without migration file, with a 10 second timeout.
+	// OnlyFiles, SkipFiles, Steps, Target and DryRun options are respected.
+	Unmark(stages []string, options ...MigrationOption) (Summary, error)
+
+	// UnmarkContext is like Unmark but uses the context.
+	UnmarkContext(ctx context.Context, stages []string, options ...MigrationOption) (Summary, error)
+
+	// Squash combines the migration files with timestamp up to the given one into
+	// one "timestamp-baseline-to-stage" file per stage written to root (the migration files directory on disk),
+	// and removes the squashed files from root. Templates are kept unrendered.
+	// Baselines list the squashed files by the "-- { squashes: name, ... }" directive and get distinct timestamps,
+	// the last stage baseline has the given timestamp. On databases with all listed files applied,
+	// Up records the baseline as applied without running it. Partially applied listed files fail the run.
+	// Files with names that can't be listed by the directive fail the squash.
+	// DryRun option resolves the baseline scripts without writing or removing files.
+	Squash(to int64, root string, options ...MigrationOption) (SquashResult, error)
 }
 
 type migration struct {
-	root  string
-	ext   string
-	dev   bool
-	files sortableFiles
-	fs    fs.FlexibleFS
-	db    MigrationSource
-	mutex sync.RWMutex
+	root        string
+	ext         string
+	dev         bool
+	table       string
+	legacyNames bool
+	data        any
+	format      FileFormat
+	version     string
+	appliedBy   string
+	host        string
+	txMode      TransactionMode
+	lockTimeout time.Duration
+	listeners   []EventListener
+	ignored     []string
+	files       sortableFiles
+	funcs       sortableFiles
+	fs          fs.FlexibleFS
+	db          MigrationSource
+	mutex       sync.RWMutex
 }
 
 // NewMigration initializes a migration with the specified database source, filesystem, and options.
 func NewMigration(db MigrationSource, fs fs.FlexibleFS, options ...Option) (Migration, error) {
+	mig, err := newMigration(db, fs, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mig.Initialize(); err != nil {
+		return nil, err
+	}
+
+	return mig, nil
+}
+
+// newMigration creates the migration and loads its files without initializing the migrations table.
+func newMigration(db MigrationSource, fs fs.FlexibleFS, options ...Option) (*migration, error) {
 	mig := &migration{
-		root:  ".",
-		ext:   "sql",
-		dev:   false,
-		files: make(sortableFiles, 0),
-		fs:    fs,
-		db:    db,
+		root:        ".",
+		ext:         "sql",
+		dev:         false,
+		table:       "migrations",
+		appliedBy:   currentUser(),
+		host:        currentHost(),
+		txMode:      TransactionSingle,
+		lockTimeout: 60 * time.Second,
+		listeners:   make([]EventListener, 0),
+		ignored:     make([]string, 0),
+		files:       make(sortableFiles, 0),
+		funcs:       make(sortableFiles, 0),
+		fs:          fs,
+		db:          db,
 	}
 
 	for _, opt := range options {
@@ -71,11 +210,6 @@ func NewMigration(db MigrationSource, fs fs.FlexibleFS, options ...Option) (Migr
 	if err := mig.Load(); err != nil {
 		return nil, err
 	}
-
-	if err := mig.Initialize(); err != nil {
-		return nil, err
-	}
-
 	return mig, nil
 }
 
@@ -91,18 +225,87 @@ func (m *migration) Load() error {
 
 	// Parse and cache migration stages from each file
 	m.files = make(sortableFiles, 0)
+	m.ignored = make([]string, 0)
 	for _, file := range files {
 		content, err := m.fs.ReadFile(file)
 		if err != nil {
 			return err
 		}
 
-		file := newMigrationFile(file, string(content))
-		if file != nil {
-			m.files = append(m.files, *file)
+		// Render template placeholders
+		path := file
+		if m.data != nil {
+			if content, err = render(path, string(content), m.data); err != nil {
+				return err
+			}
+		}
+
+		// Parse other tool layouts
+		if m.format != nil {
+			parsed, ok, err := m.format.Parse(path, string(content))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				m.ignored = append(m.ignored, path)
+				continue
+			}
+
+			m.files = m.files.merge(parsed, m.fileName(path, parsed.Name))
+			continue
+		}
+
+		file := newMigrationFile(path, string(content))
+		if file == nil {
+			m.ignored = append(m.ignored, path)
+			continue
+		} else if err := file.validate(); err != nil {
+			return err
+		}
+
+		file.name = m.fileName(path, file.name)
+		m.files = append(m.files, *file)
+	}
+
+	// Merge registered Go migrations
+	m.files = append(m.files, m.funcs...)
+	sort.Sort(m.files)
+	return nil
+}
+
+// fileName identifies nested files by path relative to root.
+func (m *migration) fileName(path, name string) string {
+	if dir := relativeDir(m.root, path); dir != "." && !m.legacyNames {
+		return dir + "/" + name
+	}
+	return name
+}
+
+func (m *migration) Register(timestamp int64, name, stage string, up, down MigrationFunc) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// Prevent collision with migration files
+	for _, file := range m.files {
+		if !file.IsFunc() && file.name == strings.TrimSpace(name) && slices.Contains(file.Stages(), strings.TrimSpace(stage)) {
+			return fmt.Errorf(`"%s" already defined for %s stage by migration file`, file.name, stage)
 		}
 	}
 
+	funcs, err := m.funcs.register(timestamp, name, stage, up, down)
+	if err != nil {
+		return err
+	}
+	m.funcs = funcs
+
+	// Replace Go migrations in cached files
+	files := make(sortableFiles, 0, len(m.files))
+	for _, file := range m.files {
+		if !file.IsFunc() {
+			files = append(files, file)
+		}
+	}
+	m.files = append(files, m.funcs...)
 	sort.Sort(m.files)
 	return nil
 }
@@ -119,26 +322,207 @@ func (m *migration) IsDev() bool {
 	return m.dev
 }
 
+// upgradeColumns lists columns added to the migrations table after its first release.
+// Initialize adds them to tables created by older versions.
+var upgradeColumns = []struct {
+	name       string
+	definition string
+}{
+	{name: "checksum", definition: "VARCHAR(64) NULL"},
+	{name: "batch", definition: "INT NULL"},
+	{name: "duration", definition: "BIGINT NULL"},
+	{name: "applied_by", definition: "VARCHAR(100) NULL"},
+	{name: "host", definition: "VARCHAR(255) NULL"},
+	{name: "version", definition: "VARCHAR(100) NULL"},
+	{name: "kind", definition: "VARCHAR(20) NULL"},
+}
+
+// kindRepeatable is the kind column value of repeatable migrations, versioned migrations have no kind.
+const kindRepeatable = "repeatable"
+
+// repeatablePrefix prefixes the stored name of repeatable migrations,
+// so they don't collide with versioned migrations of the same name and stage.
+const repeatablePrefix = "repeatable:"
+
+// lock acquires the cross-process migration lock.
+func (m *migration) lock(ctx context.Context) (func() error, error) {
+	return lockSource(ctx, m.db, "migration:"+m.table, m.lockTimeout)
+}
+
+// compile replaces the @table placeholder with the quoted migrations table name
+// and ? placeholders with the source bind parameters.
+func (m *migration) compile(query string) string {
+	return compileQuery(m.db, m.table, query)
+}
+
 func (m *migration) Initialize() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	return m.InitializeContext(ctx)
+}
+
+func (m *migration) InitializeContext(ctx context.Context) error {
+	// Serialize table creation and upgrades with other processes
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	return m.db.Exec(
+	err = m.db.Exec(
 		ctx,
-		`CREATE TABLE IF NOT EXISTS migrations (
-			name VARCHAR(100) NOT NULL,
+		m.compile(`CREATE TABLE IF NOT EXISTS @table (
+			name VARCHAR(255) NOT NULL,
 			stage VARCHAR(100) NOT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			checksum VARCHAR(64) NULL,
+			batch INT NULL,
+			duration BIGINT NULL,
+			applied_by VARCHAR(100) NULL,
+			host VARCHAR(255) NULL,
+			version VARCHAR(100) NULL,
+			kind VARCHAR(20) NULL,
 			PRIMARY KEY(name, stage)
-		);`,
+		);`),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Upgrade tables created by older versions
+	for _, column := range upgradeColumns {
+		rows, err := m.db.Scan(ctx, m.compile(fmt.Sprintf(`SELECT %s FROM @table WHERE 1 = 0;`, column.name)))
+		if err == nil {
+			rows.Close()
+			continue
+		}
+
+		err = m.db.Exec(
+			ctx,
+			m.compile(fmt.Sprintf(`ALTER TABLE @table ADD COLUMN %s %s;`, column.name, column.definition)),
+		)
+		if err != nil {
+			return fmt.Errorf("upgrade migrations table: %w", err)
+		}
+	}
+
+	if m.legacyNames {
+		return nil
+	}
+
+	if err := m.upgradeNameColumn(ctx); err != nil {
+		return fmt.Errorf("upgrade migrations table: %w", err)
+	}
+	return m.upgradeNames(ctx)
+}
+
+// nameColumnUpgrades lists per dialect the current schema function and the statement
+// widening the name column of tables created before directory qualified names.
+var nameColumnUpgrades = map[Dialect]struct {
+	schema string
+	alter  string
+}{
+	DialectPostgres: {schema: "current_schema()", alter: `ALTER TABLE @table ALTER COLUMN name TYPE VARCHAR(255);`},
+	DialectMySQL:    {schema: "DATABASE()", alter: `ALTER TABLE @table MODIFY name VARCHAR(255) NOT NULL;`},
+}
+
+// upgradeNameColumn widens the name column of tables created with VARCHAR(100) names,
+// so directory qualified names fit.
+func (m *migration) upgradeNameColumn(ctx context.Context) error {
+	upgrade, ok := nameColumnUpgrades[dialectOf(m.db)]
+	if !ok {
+		return nil
+	}
+
+	schema, table := upgrade.schema, m.table
+	arguments := []any{}
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		schema, table = "?", table[i+1:]
+		arguments = append(arguments, m.table[:i])
+	}
+	arguments = append(arguments, table)
+
+	rows, err := m.db.Scan(
+		ctx,
+		compileQuery(m.db, "", fmt.Sprintf(`SELECT character_maximum_length FROM information_schema.columns
+			WHERE table_schema = %s AND table_name = ? AND column_name = 'name';`, schema)),
+		arguments...,
 	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var length *int64
+	if rows.Next() {
+		if err := rows.Scan(&length); err != nil {
+			return err
+		}
+	}
+	if err := rowsErr(rows); err != nil {
+		return err
+	}
+
+	if length == nil || *length >= 255 {
+		return nil
+	}
+	return m.db.Exec(ctx, m.compile(upgrade.alter))
+}
+
+// upgradeNames renames rows recorded by the legacy (directory less) name of nested files
+// to their path-aware name. Legacy names shared by several files are ambiguous and left untouched.
+func (m *migration) upgradeNames(ctx context.Context) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	counts := make(map[string]int)
+	for _, file := range m.files {
+		counts[file.legacy]++
+	}
+
+	var names []string
+	for _, file := range m.files {
+		if file.IsFunc() || file.name == file.legacy || counts[file.legacy] > 1 {
+			continue
+		}
+
+		if names == nil {
+			migrated, err := m.SummaryContext(ctx)
+			if err != nil {
+				return err
+			}
+			names = migrated.Names()
+		}
+
+		if slices.Contains(names, file.name) || !slices.Contains(names, file.legacy) {
+			continue
+		}
+
+		err := m.db.Exec(
+			ctx,
+			m.compile(`UPDATE @table SET name = ? WHERE name = ?;`),
+			file.name, file.legacy,
+		)
+		if err != nil {
+			return fmt.Errorf("upgrade migration name %q: %w", file.legacy, err)
+		}
+	}
+	return nil
 }
 
 func (m *migration) Summary() (Summary, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	return m.SummaryContext(ctx)
+}
 
-	rows, err := m.db.Scan(ctx, `SELECT name, stage, created_at FROM migrations ORDER BY created_at ASC;`)
+func (m *migration) SummaryContext(ctx context.Context) (Summary, error) {
+	rows, err := m.db.Scan(
+		ctx,
+		m.compile(`SELECT
+			name, stage, created_at, checksum, batch, duration, applied_by, host, version, kind
+			FROM @table ORDER BY created_at ASC;`),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -148,20 +532,77 @@ func (m *migration) Summary() (Summary, error) {
 	for rows.Next() {
 		var name, stage string
 		var createdAt time.Time
-		err := rows.Scan(&name, &stage, &createdAt)
+		var checksum, appliedBy, host, version, kind *string
+		var batch, duration *int64
+		err := rows.Scan(&name, &stage, &createdAt, &checksum, &batch, &duration, &appliedBy, &host, &version, &kind)
 		if err != nil {
 			return nil, err
 		}
-		result = append(result, Migrated{
+
+		item := Migrated{
 			Name:      name,
 			Stage:     stage,
 			CreatedAt: createdAt,
-		})
+		}
+		if checksum != nil {
+			item.Checksum = *checksum
+		}
+		if batch != nil {
+			item.Batch = int(*batch)
+		}
+		if duration != nil {
+			item.Duration = time.Duration(*duration) * time.Millisecond
+		}
+		if appliedBy != nil {
+			item.AppliedBy = *appliedBy
+		}
+		if host != nil {
+			item.Host = *host
+		}
+		if version != nil {
+			item.Version = *version
+		}
+		if kind != nil && *kind == kindRepeatable {
+			item.Repeatable = true
+			item.Name = strings.TrimPrefix(item.Name, repeatablePrefix)
+		}
+		result = append(result, item)
 	}
 	return result, nil
 }
 
 func (m *migration) Up(stages []string, options ...MigrationOption) (Summary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+	return m.migrate(ctx, actionUp, stages, options...)
+}
+
+func (m *migration) UpContext(ctx context.Context, stages []string, options ...MigrationOption) (Summary, error) {
+	return m.migrate(ctx, actionUp, stages, options...)
+}
+
+func (m *migration) Down(stages []string, options ...MigrationOption) (Summary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+	return m.migrate(ctx, actionDown, stages, options...)
+}
+
+func (m *migration) DownContext(ctx context.Context, stages []string, options ...MigrationOption) (Summary, error) {
+	return m.migrate(ctx, actionDown, stages, options...)
+}
+
+func (m *migration) Refresh(stages []string, options ...MigrationOption) (Summary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+	return m.migrate(ctx, actionRefresh, stages, options...)
+}
+
+func (m *migration) RefreshContext(ctx context.Context, stages []string, options ...MigrationOption) (Summary, error) {
+	return m.migrate(ctx, actionRefresh, stages, options...)
+}
+
+// migrate plans and executes the action for the given stages.
+func (m *migration) migrate(ctx context.Context, action action, stages []string, options ...MigrationOption) (Summary, error) {
 	if len(stages) == 0 {
 		return nil, nil
 	}
@@ -182,68 +623,414 @@ func (m *migration) Up(stages []string, options ...MigrationOption) (Summary, er
 		opt(option)
 	}
 
+	// Plan only on dry-run
+	if option.plan != nil {
+		migrated, err := m.SummaryContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		plan, err := m.plan(action, stages, migrated, option)
+		if err != nil {
+			return nil, err
+		}
+
+		*option.plan = plan
+		return option.plan.summary(action.reports(), migrated.LastBatch()+1), nil
+	}
+
+	// Acquire lock
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	// Read migrated files
-	migrated, err := m.Summary()
+	migrated, err := m.SummaryContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filter files
-	files := m.files.Filter(option.only.Elements(), option.exclude.Elements())
-	if files.Len() == 0 {
+	// Plan scripts
+	plan, err := m.plan(action, stages, migrated, option)
+	if err != nil {
+		return nil, err
+	} else if plan.IsEmpty() {
 		return nil, nil
 	}
 
 	// Execute scripts
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
-	defer cancel()
-	result := make(Summary, 0)
-	err = m.db.Transaction(ctx, func(tx ExecutableScanner) error {
-		for _, stage := range stages {
+	start := time.Now()
+	m.emit(func(l EventListener) { l.BeforeAll(ctx, plan) })
+	result, err := m.execute(ctx, plan, action.reports(), migrated.LastBatch()+1)
+	m.emit(func(l EventListener) { l.AfterAll(ctx, result, time.Since(start), err) })
+	return result, err
+}
+
+// plan resolves the ordered steps to run for the action.
+func (m *migration) plan(action action, stages []string, migrated Summary, option *migrationOption) (Plan, error) {
+	files := m.files.Filter(option.only.Elements(), option.exclude.Elements())
+	if option.target != nil {
+		if action.forward() {
+			files = files.Until(*option.target)
+		} else {
+			files = files.After(*option.target)
+		}
+	}
+
+	// Resolve rollback batches
+	batches := migrated.Batches()
+	if option.batches > 0 && option.batches < len(batches) {
+		batches = batches[:option.batches]
+	}
+
+	result := make(Plan, 0)
+	for _, stage := range stages {
+		rolledBack := make(map[string]bool)
+
+		// Down
+		if action == actionDown || action == actionRefresh {
+			for _, file := range files.Reverse() {
+				item, ok := migrated.find(file.name, stage)
+				if !ok || (option.batches > 0 && !slices.Contains(batches, item.Batch)) {
+					continue
+				}
+
+				step, ok := file.DownStep(stage)
+				if !ok {
+					continue
+				}
+
+				rolledBack[file.name] = true
+				result = append(result, step)
+			}
+		}
+
+		// Up
+		if action == actionUp || action == actionRefresh {
 			for _, file := range files {
-				if migrated.includes(file.name, stage) {
+				if migrated.includes(file.name, stage) && !rolledBack[file.name] {
 					continue
 				}
 
-				script, ok := file.UpScript(stage)
-				if !ok || len(script) == 0 {
+				// Refresh of batches reapplies rolled back files only
+				if action == actionRefresh && option.batches > 0 && !rolledBack[file.name] {
 					continue
 				}
 
-				err := tx.Exec(ctx, script)
-				if err != nil {
-					return fmt.Errorf("%s: %w", file.name, err)
+				step, ok := file.UpStep(stage)
+				if !ok {
+					continue
 				}
 
-				err = tx.Exec(
-					ctx,
-					fmt.Sprintf(`INSERT INTO migrations (name, stage) VALUES ('%s', '%s');`, file.name, stage),
-				)
-				if err != nil {
-					return fmt.Errorf("%s: %w", file.name, err)
+				// Baselines are recorded without running on databases with squashed files applied
+				if file.IsBaseline() {
+					replaces, err := squashed(file, stage, migrated)
+					if err != nil {
+						return nil, err
+					}
+					step.replaces = replaces
+					step.Baseline = len(replaces) > 0
 				}
 
-				result = append(result, Migrated{
-					Stage:     stage,
-					Name:      file.name,
-					CreatedAt: time.Now(),
+				result = append(result, step)
+			}
+
+			// Repeatable files run after versioned files when changed
+			for _, file := range files {
+				step, ok := file.RepeatableStep(stage)
+				if !ok {
+					continue
+				}
+
+				item, ok := migrated.findRepeatable(file.name, stage)
+				if ok && item.Checksum == checksum(step.Script) {
+					continue
+				}
+
+				result = append(result, step)
+			}
+		}
+	}
+
+	// Limit to the first (or last on rollback) versioned files
+	if option.steps > 0 {
+		ordered := files
+		if !action.forward() {
+			ordered = files.Reverse()
+		}
+
+		selected := make(sortableFiles, 0)
+		for _, file := range ordered {
+			if len(selected) == option.steps {
+				break
+			}
+
+			planned := slices.ContainsFunc(result, func(step Step) bool {
+				return !step.Repeatable && step.of(file)
+			})
+			if planned {
+				selected = append(selected, file)
+			}
+		}
+		result = result.limit(selected)
+	}
+
+	if action == actionUp {
+		return result, nil
+	}
+	return m.resetRepeatables(result, action, stages, migrated, option), nil
+}
+
+// resetRepeatables resets the repeatable files of stages with rolled back files,
+// as their views or functions may depend on the rolled back objects.
+// Down clears their entries to rerun them on the next Up, Refresh reruns them after the stage.
+func (m *migration) resetRepeatables(plan Plan, action action, stages []string, migrated Summary, option *migrationOption) Plan {
+	files := m.files.Filter(option.only.Elements(), option.exclude.Elements())
+
+	result := make(Plan, 0, len(plan))
+	for _, stage := range stages {
+		steps := make(Plan, 0)
+		rolledBack := false
+		for _, step := range plan {
+			if step.Stage == stage {
+				steps = append(steps, step)
+				rolledBack = rolledBack || (step.Direction == DirectionDown && !step.Repeatable)
+			}
+		}
+
+		if !rolledBack {
+			result = append(result, steps...)
+			continue
+		}
+
+		resets := make(Plan, 0)
+		for _, file := range files {
+			step, ok := file.RepeatableStep(stage)
+			if !ok {
+				continue
+			}
+
+			if action == actionRefresh {
+				planned := slices.ContainsFunc(steps, func(s Step) bool {
+					return s.Repeatable && s.Name == file.name
+				})
+				if !planned {
+					resets = append(resets, step)
+				}
+			} else if _, ok := migrated.findRepeatable(file.name, stage); ok {
+				resets = append(resets, Step{
+					Name:          step.Name,
+					Stage:         step.Stage,
+					Direction:     DirectionDown,
+					NoTransaction: step.NoTransaction,
+					Repeatable:    true,
+					timestamp:     step.timestamp,
 				})
 			}
 		}
-		return nil
-	})
 
-	if err != nil {
-		return nil, err
+		if action == actionRefresh {
+			result = append(append(result, steps...), resets...)
+		} else {
+			result = append(append(result, resets...), steps...)
+		}
 	}
-	return result, nil
+	return result
 }
 
-func (m *migration) Down(stages []string, options ...MigrationOption) (Summary, error) {
-	if len(stages) == 0 {
+// squashed returns the files listed by the baseline "squashes" directive when all of them are applied in the stage,
+// i.e. the entries replaced by the baseline. Returns nil when none is applied and an error when some are.
+func squashed(file migrationFile, stage string, migrated Summary) ([]string, error) {
+	missing := make([]string, 0)
+	for _, name := range file.squashes {
+		if !migrated.includes(name, stage) {
+			missing = append(missing, name)
+		}
+	}
+
+	switch len(missing) {
+	case 0:
+		return file.squashes, nil
+	case len(file.squashes):
 		return nil, nil
 	}
+	return nil, fmt.Errorf(
+		`baseline "%s" squashes files partially applied on %s stage, missing: %s`,
+		file.name, stage, strings.Join(missing, ", "),
+	)
+}
 
+// execute runs the plan steps in order and returns the applied steps reported in the direction.
+// Applied steps are recorded with the batch number.
+// Steps are grouped into transactions by the transaction mode, non-transactional steps run directly on the source.
+// On failure, steps committed before the failing transaction are returned along with a *MigrationError.
+func (m *migration) execute(ctx context.Context, plan Plan, report Direction, batch int) (Summary, error) {
+	result := make(Summary, 0)
+	for i := 0; i < len(plan); {
+		// Run non-transactional step
+		if plan[i].NoTransaction {
+			step := plan[i]
+			item, err := m.apply(ctx, m.db, step, batch)
+			if err != nil {
+				return result, newMigrationError(step, err)
+			}
+
+			m.emit(func(l EventListener) { l.AfterFile(ctx, step, item.Duration) })
+			if step.Direction == report {
+				result = append(result, item)
+			}
+			i++
+			continue
+		}
+
+		// Run transactional steps batch
+		j := i + 1
+		for j < len(plan) && !plan[j].NoTransaction && m.txMode.shares(plan[j-1], plan[j]) {
+			j++
+		}
+
+		applied := make(Summary, 0)
+		executed := make([]Migrated, 0, j-i)
+		err := m.db.Transaction(ctx, func(tx ExecutableScanner) error {
+			for _, step := range plan[i:j] {
+				item, err := m.apply(ctx, tx, step, batch)
+				if err != nil {
+					return newMigrationError(step, err)
+				}
+
+				executed = append(executed, item)
+				if step.Direction == report {
+					applied = append(applied, item)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+
+		// Notify once the steps are committed
+		for k, step := range plan[i:j] {
+			duration := executed[k].Duration
+			m.emit(func(l EventListener) { l.AfterFile(ctx, step, duration) })
+		}
+
+		result = append(result, applied...)
+		i = j
+	}
+	return result, nil
+}
+
+// apply runs the step and records it in the migrations table, notifying listeners of the start and failure.
+// AfterFile is emitted by the caller once the step is committed.
+func (m *migration) apply(ctx context.Context, tx ExecutableScanner, step Step, batch int) (Migrated, error) {
+	start := time.Now()
+	m.emit(func(l EventListener) { l.BeforeFile(ctx, step) })
+
+	item, err := m.run(ctx, tx, step, batch, start)
+	if err != nil {
+		m.emit(func(l EventListener) { l.OnError(ctx, step, time.Since(start), err) })
+		return item, err
+	}
+	return item, nil
+}
+
+// run executes the step script or function and records it in the migrations table.
+func (m *migration) run(ctx context.Context, tx ExecutableScanner, step Step, batch int, start time.Time) (Migrated, error) {
+	if !step.Baseline {
+		if err := m.exec(ctx, tx, step); err != nil {
+			return Migrated{}, err
+		}
+	}
+
+	return m.record(ctx, tx, step, batch, time.Since(start))
+}
+
+// record inserts the up step, or deletes the down step, in the migrations table.
+func (m *migration) record(ctx context.Context, tx ExecutableScanner, step Step, batch int, duration time.Duration) (Migrated, error) {
+	item := step.migrated(batch)
+	item.Duration = duration
+	if step.Direction == DirectionDown {
+		return item, m.remove(ctx, tx, item.Name, item.Stage, step.Repeatable)
+	}
+
+	// Replace the entries of squashed files by the baseline
+	for _, name := range step.replaces {
+		if err := m.remove(ctx, tx, name, item.Stage, false); err != nil {
+			return item, err
+		}
+	}
+
+	// Replace the previous run of repeatable files
+	name := item.Name
+	var kind *string
+	if step.Repeatable {
+		kind = new(string)
+		*kind = kindRepeatable
+		name = repeatablePrefix + item.Name
+		if err := m.remove(ctx, tx, item.Name, item.Stage, true); err != nil {
+			return item, err
+		}
+	}
+
+	item.AppliedBy = m.appliedBy
+	item.Host = m.host
+	item.Version = m.version
+	return item, tx.Exec(
+		ctx,
+		m.compile(`INSERT INTO @table
+			(name, stage, checksum, batch, duration, applied_by, host, version, kind)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`),
+		name, item.Stage, item.Checksum, item.Batch,
+		item.Duration.Milliseconds(), item.AppliedBy, item.Host, item.Version, kind,
+	)
+}
+
+// remove deletes the versioned or repeatable entry of the file stage from the migrations table.
+// Repeatable entries are stored with prefixed name, those recorded before the prefix are removed too.
+func (m *migration) remove(ctx context.Context, tx ExecutableScanner, name, stage string, repeatable bool) error {
+	if repeatable {
+		return tx.Exec(
+			ctx,
+			m.compile(`DELETE FROM @table WHERE name IN (?, ?) AND stage = ? AND kind = ?;`),
+			repeatablePrefix+name, name, stage, kindRepeatable,
+		)
+	}
+
+	return tx.Exec(
+		ctx,
+		m.compile(`DELETE FROM @table WHERE name = ? AND stage = ?;`),
+		name, stage,
+	)
+}
+
+// exec runs the step function or script statements without recording it.
+func (m *migration) exec(ctx context.Context, tx ExecutableScanner, step Step) error {
+	if step.IsFunc() {
+		return step.fn(ctx, tx)
+	}
+
+	for _, statement := range splitScript(m.db, step.Script) {
+		if err := tx.Exec(ctx, statement.SQL); err != nil {
+			return &StatementError{
+				Line:      step.fileLine(statement.Line),
+				Statement: statement.SQL,
+				Err:       err,
+			}
+		}
+	}
+	return nil
+}
+
+func (m *migration) Status() (StatusReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return m.StatusContext(ctx)
+}
+
+func (m *migration) StatusContext(ctx context.Context) (StatusReport, error) {
 	// Hot reload on dev mode
 	if m.dev {
 		if err := m.Load(); err != nil {
@@ -254,75 +1041,90 @@ func (m *migration) Down(stages []string, options ...MigrationOption) (Summary,
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
-	// Create option
-	option := newOption()
-	for _, opt := range options {
-		opt(option)
-	}
-
 	// Read migrated files
-	migrated, err := m.Summary()
+	migrated, err := m.SummaryContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filter files
-	files := m.files.Reverse().Filter(option.only.Elements(), option.exclude.Elements())
-	if files.Len() == 0 {
-		return nil, nil
-	}
-
-	// Execute scripts
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
-	defer cancel()
-	result := make(Summary, 0)
-	err = m.db.Transaction(ctx, func(tx ExecutableScanner) error {
-		for _, stage := range stages {
-			for _, file := range files {
-				if !migrated.includes(file.name, stage) {
-					continue
-				}
+	// Join files with migrated entries
+	result := make(StatusReport, 0)
+	known := make(map[string]bool)
+	for _, file := range m.files {
+		for _, stage := range file.Stages() {
+			key := file.name + "\x00" + stage
+			known[key] = true
 
-				script, ok := file.DownScript(stage)
-				if !ok {
-					continue
-				}
+			if item, ok := migrated.find(file.name, stage); ok {
+				result = append(result, StatusEntry{
+					Name:      file.name,
+					Stage:     stage,
+					State:     StateApplied,
+					AppliedAt: item.CreatedAt,
+				})
+			} else if _, ok := file.UpStep(stage); ok {
+				result = append(result, StatusEntry{
+					Name:  file.name,
+					Stage: stage,
+					State: StatePending,
+				})
+			}
+		}
 
-				if len(script) != 0 {
-					if err := tx.Exec(ctx, script); err != nil {
-						return fmt.Errorf("%s: %w", file.name, err)
-					}
-				}
+		// Changed repeatable files are pending
+		for _, stage := range file.RepeatableStages() {
+			step, ok := file.RepeatableStep(stage)
+			if !ok {
+				continue
+			}
 
-				err = tx.Exec(
-					ctx,
-					fmt.Sprintf(`DELETE FROM migrations WHERE name = '%s' AND stage = '%s';`, file.name, stage),
-				)
-				if err != nil {
-					return fmt.Errorf("%s: %w", file.name, err)
-				}
+			key := file.name + "\x00" + stage + "\x00" + kindRepeatable
+			known[key] = true
 
-				result = append(result, Migrated{
-					Stage:     stage,
+			item, ok := migrated.findRepeatable(file.name, stage)
+			if ok && item.Checksum == checksum(step.Script) {
+				result = append(result, StatusEntry{
 					Name:      file.name,
-					CreatedAt: time.Now(),
+					Stage:     stage,
+					State:     StateApplied,
+					AppliedAt: item.CreatedAt,
+				})
+			} else {
+				result = append(result, StatusEntry{
+					Name:  file.name,
+					Stage: stage,
+					State: StatePending,
 				})
 			}
 		}
-		return nil
-	})
+	}
 
-	if err != nil {
-		return nil, err
+	// Append migrated entries without file
+	for _, item := range migrated {
+		key := item.Name + "\x00" + item.Stage
+		if item.Repeatable {
+			key += "\x00" + kindRepeatable
+		}
+
+		if !known[key] {
+			result = append(result, StatusEntry{
+				Name:      item.Name,
+				Stage:     item.Stage,
+				State:     StateOrphaned,
+				AppliedAt: item.CreatedAt,
+			})
+		}
 	}
 	return result, nil
 }
 
-func (m *migration) Refresh(stages []string, options ...MigrationOption) (Summary, error) {
-	if len(stages) == 0 {
-		return nil, nil
-	}
+func (m *migration) Verify() ([]Drift, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return m.VerifyContext(ctx)
+}
 
+func (m *migration) VerifyContext(ctx context.Context) ([]Drift, error) {
 	// Hot reload on dev mode
 	if m.dev {
 		if err := m.Load(); err != nil {
@@ -333,84 +1135,137 @@ func (m *migration) Refresh(stages []string, options ...MigrationOption) (Summar
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
-	// Create option
-	option := newOption()
-	for _, opt := range options {
-		opt(option)
-	}
-
 	// Read migrated files
-	migrated, err := m.Summary()
+	migrated, err := m.SummaryContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filter upFiles
-	downFiles := m.files.Reverse().Filter(option.only.Elements(), option.exclude.Elements())
-	upFiles := m.files.Filter(option.only.Elements(), option.exclude.Elements())
+	// Compare stored checksums with current scripts
+	result := make([]Drift, 0)
+	for _, item := range migrated {
+		if item.Repeatable {
+			continue
+		}
 
-	// Execute scripts
+		drift := Drift{
+			Name:     item.Name,
+			Stage:    item.Stage,
+			Applied:  item.Checksum,
+			Migrated: item.CreatedAt,
+		}
+
+		file, ok := m.files.Find(item.Name)
+		if ok && file.IsFunc() {
+			continue
+		}
+		if ok {
+			if script, ok := file.UpScript(item.Stage); ok {
+				drift.Current = checksum(script)
+			}
+		}
+
+		switch {
+		case drift.Current == "":
+			drift.State = DriftRemoved
+		case item.Checksum == "":
+			drift.State = DriftUnknown
+		case item.Checksum != drift.Current:
+			drift.State = DriftChanged
+		default:
+			continue
+		}
+		result = append(result, drift)
+	}
+	return result, nil
+}
+
+func (m *migration) Import(format FileFormat, files ...string) (Summary, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
 	defer cancel()
-	result := make(Summary, 0)
-	err = m.db.Transaction(ctx, func(tx ExecutableScanner) error {
-		for _, stage := range stages {
-			// Down
-			for _, file := range downFiles {
-				if !migrated.includes(file.name, stage) {
-					continue
-				}
+	return m.ImportContext(ctx, format, files...)
+}
 
-				script, ok := file.DownScript(stage)
-				if !ok {
-					continue
-				}
+func (m *migration) ImportContext(ctx context.Context, format FileFormat, files ...string) (Summary, error) {
+	if m.format == nil && len(files) == 0 {
+		return nil, errors.New("converted file names are required to import native migration files")
+	}
 
-				if len(script) != 0 {
-					if err := tx.Exec(ctx, script); err != nil {
-						return fmt.Errorf(`rollback "%s": %w`, file.name, err)
-					}
-				}
+	// Hot reload on dev mode
+	if m.dev {
+		if err := m.Load(); err != nil {
+			return nil, err
+		}
+	}
 
-				err = tx.Exec(
-					ctx,
-					fmt.Sprintf(`DELETE FROM migrations WHERE name = '%s' AND stage = '%s';`, file.name, stage),
-				)
-				if err != nil {
-					return fmt.Errorf(`rollback "%s": %w`, file.name, err)
-				}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	// Acquire lock
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Resolve imported files
+	imported := make(sortableFiles, 0)
+	versions := make([]int64, 0)
+	for _, file := range m.files {
+		if file.IsFunc() {
+			continue
+		}
+		if len(files) > 0 && !slices.Contains(files, convertedFileName(file.timestamp, file.legacy, file.extension)) {
+			continue
+		}
+		imported = append(imported, file)
+		versions = append(versions, file.timestamp)
+	}
+
+	// Files converted outside of root are never loaded
+	for _, name := range files {
+		if !slices.ContainsFunc(imported, func(file migrationFile) bool {
+			return convertedFileName(file.timestamp, file.legacy, file.extension) == name
+		}) {
+			return nil, fmt.Errorf(`"%s" converted file is not in the migration root`, name)
+		}
+	}
+
+	// Read versions applied by the other tool
+	applied, err := format.Applied(ctx, m.db, versions)
+	if err != nil {
+		return nil, fmt.Errorf("read history table: %w", err)
+	}
+
+	migrated, err := m.SummaryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record applied files without running them
+	result := make(Summary, 0)
+	batch := migrated.LastBatch() + 1
+	err = m.db.Transaction(ctx, func(tx ExecutableScanner) error {
+		for _, file := range imported {
+			if !slices.Contains(applied, file.timestamp) {
+				continue
 			}
 
-			// Up
-			for _, file := range upFiles {
-				script, ok := file.UpScript(stage)
-				if !ok || len(script) == 0 {
+			for _, stage := range file.Stages() {
+				step, ok := file.UpStep(stage)
+				if !ok || migrated.includes(file.name, stage) {
 					continue
 				}
 
-				err := tx.Exec(ctx, script)
+				item, err := m.record(ctx, tx, step, batch, 0)
 				if err != nil {
-					return fmt.Errorf(`up "%s": %w`, file.name, err)
+					return fmt.Errorf("import %q: %w", item.Name, err)
 				}
-
-				err = tx.Exec(
-					ctx,
-					fmt.Sprintf(`INSERT INTO migrations (name, stage) VALUES ('%s', '%s');`, file.name, stage),
-				)
-				if err != nil {
-					return fmt.Errorf(`up "%s": %w`, file.name, err)
-				}
-
-				result = append(result, Migrated{
-					Stage:     stage,
-					Name:      file.name,
-					CreatedAt: time.Now(),
-				})
+				result = append(result, item)
 			}
 		}
 		return nil
 	})
-
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,239 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedDialect is returned by operations not supported by the source dialect.
+var ErrUnsupportedDialect = errors.New("operation not supported by the source dialect")
+
+// Finding describes a risky statement reported by Analyze.
+type Finding struct {
+	Rule       string `json:"rule" yaml:"rule"`
+	Name       string `json:"name" yaml:"name"`
+	Stage      string `json:"stage" yaml:"stage"`
+	Line       int    `json:"line" yaml:"line"` // Line of the statement in the migration file.
+	Statement  string `json:"statement" yaml:"statement"`
+	Message    string `json:"message" yaml:"message"`
+	Suggestion string `json:"suggestion" yaml:"suggestion"` // Safer alternative.
+}
+
+// analyzerRule matches a risky Postgres statement.
+type analyzerRule struct {
+	name       string
+	match      func(stmt analyzedStatement) bool
+	message    string
+	suggestion string
+}
+
+// analyzedStatement is a statement normalized for matching:
+// comments and literals removed, white spaces collapsed and upper cased.
+type analyzedStatement struct {
+	sql   string
+	table string // Target table of CREATE INDEX and ALTER TABLE statements.
+	fresh bool   // Target table is created by the same script.
+}
+
+var (
+	analyzerLineComment  = regexp.MustCompile(`--[^\n]*`)
+	analyzerBlockComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	analyzerLiteral      = regexp.MustCompile(`'(?:[^']|'')*'`)
+	analyzerCreateTable  = regexp.MustCompile(`^CREATE (?:UNLOGGED |TEMP |TEMPORARY )?TABLE (?:IF NOT EXISTS )?([\w."]+)`)
+	analyzerCreateIndex  = regexp.MustCompile(`^CREATE (?:UNIQUE )?INDEX (?:CONCURRENTLY )?(?:IF NOT EXISTS )?(?:[\w"]+ )?ON (?:ONLY )?([\w."]+)`)
+	analyzerAlterTable   = regexp.MustCompile(`^ALTER TABLE (?:IF EXISTS )?(?:ONLY )?([\w."]+)`)
+	analyzerVolatile     = regexp.MustCompile(`\bDEFAULT \(?(?:RANDOM|GEN_RANDOM_UUID|UUID_GENERATE_V1|UUID_GENERATE_V4|CLOCK_TIMESTAMP|TIMEOFDAY|NEXTVAL) ?\(`)
+	analyzerColumnType   = regexp.MustCompile(`\bALTER (?:COLUMN )?\S+ (?:SET DATA )?TYPE\b`)
+	analyzerSetNotNull   = regexp.MustCompile(`\bALTER (?:COLUMN )?\S+ SET NOT NULL\b`)
+	analyzerForeignKey   = regexp.MustCompile(`\b(?:FOREIGN KEY|REFERENCES)\b`)
+	analyzerCheck        = regexp.MustCompile(`\bADD (?:CONSTRAINT \S+ )?CHECK\b`)
+)
+
+// analyzerRules lists the Postgres statements taking long ACCESS EXCLUSIVE (or SHARE) locks.
+// Statements on tables created by the same script are safe and never reported.
+var analyzerRules = []analyzerRule{
+	{
+		name: "create-index",
+		match: func(s analyzedStatement) bool {
+			return strings.HasPrefix(s.sql, "CREATE ") && s.table != "" && !s.fresh &&
+				!strings.Contains(s.sql, " CONCURRENTLY ")
+		},
+		message:    "CREATE INDEX blocks writes to the table until the index is built",
+		suggestion: "use CREATE INDEX CONCURRENTLY in a file with the \"-- { options: no-transaction }\" directive",
+	},
+	{
+		name: "drop-index",
+		match: func(s analyzedStatement) bool {
+			return strings.HasPrefix(s.sql, "DROP INDEX ") && !strings.Contains(s.sql, " CONCURRENTLY ")
+		},
+		message:    "DROP INDEX takes an ACCESS EXCLUSIVE lock on the table",
+		suggestion: "use DROP INDEX CONCURRENTLY in a file with the \"-- { options: no-transaction }\" directive",
+	},
+	{
+		name: "volatile-default",
+		match: func(s analyzedStatement) bool {
+			return isAlterTable(s) && strings.Contains(s.sql, " ADD ") && analyzerVolatile.MatchString(s.sql)
+		},
+		message:    "adding a column with a volatile default rewrites the table under an ACCESS EXCLUSIVE lock",
+		suggestion: "add the column without default, backfill it in batches, then set the default and NOT NULL",
+	},
+	{
+		name: "column-type",
+		match: func(s analyzedStatement) bool {
+			return isAlterTable(s) && analyzerColumnType.MatchString(s.sql)
+		},
+		message:    "changing a column type rewrites the table and its indexes under an ACCESS EXCLUSIVE lock",
+		suggestion: "add a new column, backfill it and switch to it, unless the change is binary compatible (e.g. longer VARCHAR)",
+	},
+	{
+		name: "set-not-null",
+		match: func(s analyzedStatement) bool {
+			return isAlterTable(s) && analyzerSetNotNull.MatchString(s.sql)
+		},
+		message:    "SET NOT NULL scans the whole table under an ACCESS EXCLUSIVE lock",
+		suggestion: "add a CHECK (column IS NOT NULL) NOT VALID constraint, validate it, then SET NOT NULL",
+	},
+	{
+		name: "foreign-key",
+		match: func(s analyzedStatement) bool {
+			return isAlterTable(s) && analyzerForeignKey.MatchString(s.sql) && !strings.Contains(s.sql, " NOT VALID")
+		},
+		message:    "adding a foreign key validates all rows while locking both tables",
+		suggestion: "add the constraint with NOT VALID, then VALIDATE CONSTRAINT in a separate migration",
+	},
+	{
+		name: "check-constraint",
+		match: func(s analyzedStatement) bool {
+			return isAlterTable(s) && analyzerCheck.MatchString(s.sql) && !strings.Contains(s.sql, " NOT VALID")
+		},
+		message:    "adding a check constraint scans the whole table under an ACCESS EXCLUSIVE lock",
+		suggestion: "add the constraint with NOT VALID, then VALIDATE CONSTRAINT in a separate migration",
+	},
+}
+
+// isAlterTable reports whether the statement alters a table not created by the same script.
+func isAlterTable(s analyzedStatement) bool {
+	return strings.HasPrefix(s.sql, "ALTER TABLE ") && s.table != "" && !s.fresh
+}
+
+func (m *migration) Analyze(stages ...string) ([]Finding, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return m.AnalyzeContext(ctx, stages...)
+}
+
+func (m *migration) AnalyzeContext(ctx context.Context, stages ...string) ([]Finding, error) {
+	if dialectOf(m.db) != DialectPostgres {
+		return nil, ErrUnsupportedDialect
+	}
+
+	// Hot reload on dev mode
+	if m.dev {
+		if err := m.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	// Files are rendered on Load
+	result := make([]Finding, 0)
+	for _, file := range m.files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, stage := range file.Stages() {
+			if len(stages) > 0 && !slices.Contains(stages, stage) {
+				continue
+			}
+
+			step, ok := file.UpStep(stage)
+			if !ok || step.IsFunc() {
+				continue
+			}
+			result = append(result, analyzeScript(step, file.allowed)...)
+		}
+	}
+	return result, nil
+}
+
+// analyzeScript reports risky statements of the Postgres script.
+// Rules listed in allowed (or "all") are suppressed.
+func analyzeScript(step Step, allowed []string) []Finding {
+	if slices.Contains(allowed, "all") {
+		return nil
+	}
+
+	result := make([]Finding, 0)
+	created := make([]string, 0)
+	for _, statement := range SplitStatements(step.Script, DialectPostgres) {
+		stmt := normalizeStatement(statement.SQL)
+		if matches := analyzerCreateTable.FindStringSubmatch(stmt.sql); len(matches) == 2 {
+			created = append(created, matches[1])
+			continue
+		}
+
+		if matches := analyzerCreateIndex.FindStringSubmatch(stmt.sql); len(matches) == 2 {
+			stmt.table = matches[1]
+		} else if matches := analyzerAlterTable.FindStringSubmatch(stmt.sql); len(matches) == 2 {
+			stmt.table = matches[1]
+		}
+		stmt.fresh = stmt.table != "" && slices.ContainsFunc(created, func(table string) bool {
+			return sameTable(table, stmt.table)
+		})
+
+		for _, rule := range analyzerRules {
+			if slices.Contains(allowed, rule.name) {
+				continue
+			}
+
+			if rule.match(stmt) {
+				result = append(result, Finding{
+					Rule:       rule.name,
+					Name:       step.Name,
+					Stage:      step.Stage,
+					Line:       step.fileLine(statement.Line),
+					Statement:  statement.SQL,
+					Message:    rule.message,
+					Suggestion: rule.suggestion,
+				})
+			}
+		}
+	}
+	return result
+}
+
+// sameTable reports whether the table names refer to the same table.
+// Unqualified names match any schema, e.g. "users" and "public.users".
+func sameTable(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	schemaA, nameA, qualifiedA := strings.Cut(a, ".")
+	schemaB, nameB, qualifiedB := strings.Cut(b, ".")
+	switch {
+	case qualifiedA && qualifiedB:
+		return schemaA == schemaB && nameA == nameB
+	case qualifiedA:
+		return nameA == b
+	case qualifiedB:
+		return nameB == a
+	}
+	return false
+}
+
+// normalizeStatement removes comments and literals, collapses white spaces and upper cases the statement.
+func normalizeStatement(sql string) analyzedStatement {
+	sql = analyzerBlockComment.ReplaceAllString(sql, " ")
+	sql = analyzerLineComment.ReplaceAllString(sql, " ")
+	sql = analyzerLiteral.ReplaceAllString(sql, "''")
+	sql = strings.ToUpper(strings.Join(strings.Fields(sql), " "))
+	return analyzedStatement{sql: strings.ReplaceAll(sql, `"`, "") + " "}
+}
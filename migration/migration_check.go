@@ -0,0 +1,239 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ReversibilityReport is the reversibility check result of a file stage.
+type ReversibilityReport struct {
+	Name     string   `json:"name" yaml:"name"`
+	Stage    string   `json:"stage" yaml:"stage"`
+	Missing  []string `json:"missing" yaml:"missing"`   // Schema objects existing before up and missing after down.
+	Leftover []string `json:"leftover" yaml:"leftover"` // Schema objects created by up and left after down.
+	Error    string   `json:"error" yaml:"error"`       // Failure of up, down or reapplied up, or skip reason.
+}
+
+// Reversible reports whether down restored the schema and up could be reapplied.
+func (r ReversibilityReport) Reversible() bool {
+	return len(r.Missing) == 0 && len(r.Leftover) == 0 && r.Error == ""
+}
+
+// errCheckRollback rolls back the reversibility check transaction.
+var errCheckRollback = errors.New("rollback reversibility check")
+
+func (m *migration) CheckReversible(stages []string, options ...MigrationOption) ([]ReversibilityReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+	return m.CheckReversibleContext(ctx, stages, options...)
+}
+
+func (m *migration) CheckReversibleContext(ctx context.Context, stages []string, options ...MigrationOption) ([]ReversibilityReport, error) {
+	if len(stages) == 0 {
+		return nil, nil
+	}
+
+	// Hot reload on dev mode
+	if m.dev {
+		if err := m.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	// Create option
+	option := newOption()
+	for _, opt := range options {
+		opt(option)
+	}
+
+	// DDL is only rolled back on Postgres, check other dialects on a scratch database
+	source := m.db
+	if option.scratch != nil {
+		source = option.scratch
+	} else if dialectOf(m.db) != DialectPostgres {
+		return nil, ErrUnsupportedDialect
+	}
+
+	// Acquire lock
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	migrated, err := m.SummaryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Run pending steps in a rollback-only transaction
+	plan, err := m.plan(actionUp, stages, migrated, option)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]ReversibilityReport, 0)
+	err = source.Transaction(ctx, func(tx ExecutableScanner) error {
+		for _, up := range plan {
+			// Repeatable files are applied without check
+			if up.Repeatable {
+				if err := m.exec(ctx, tx, up); err != nil {
+					return err
+				}
+				continue
+			}
+
+			result := ReversibilityReport{Name: up.Name, Stage: up.Stage}
+			if up.NoTransaction {
+				result.Error = "skipped, no-transaction files can't run in a rollback-only transaction"
+				reports = append(reports, result)
+				continue
+			}
+
+			// Baselines replacing applied files are recorded without running
+			if up.Baseline {
+				result.Error = "skipped, baseline is recorded without running"
+				reports = append(reports, result)
+				continue
+			}
+
+			file, _ := m.files.Find(up.Name)
+			down, ok := file.DownStep(up.Stage)
+			if !ok {
+				result.Error = "down section is missing"
+				reports = append(reports, result)
+				if err := m.exec(ctx, tx, up); err != nil {
+					return err
+				}
+				continue
+			}
+
+			failed, err := m.checkStep(ctx, tx, dialectOf(source), up, down, &result)
+			reports = append(reports, result)
+			if err != nil {
+				return err
+			} else if failed {
+				return errCheckRollback
+			}
+		}
+		return errCheckRollback
+	})
+	if err != nil && !errors.Is(err, errCheckRollback) {
+		return reports, err
+	}
+	return reports, nil
+}
+
+// checkStep runs up, down and up again, comparing the schema before up and after down.
+// Returns true when a step fails, aborting the transaction.
+func (m *migration) checkStep(ctx context.Context, tx ExecutableScanner, dialect Dialect, up, down Step, result *ReversibilityReport) (bool, error) {
+	before, err := snapshot(ctx, tx, dialect)
+	if err != nil {
+		return true, err
+	}
+
+	if err := m.exec(ctx, tx, up); err != nil {
+		result.Error = fmt.Sprintf("up: %v", err)
+		return true, nil
+	}
+
+	if err := m.exec(ctx, tx, down); err != nil {
+		result.Error = fmt.Sprintf("down: %v", err)
+		return true, nil
+	}
+
+	after, err := snapshot(ctx, tx, dialect)
+	if err != nil {
+		return true, err
+	}
+
+	result.Missing = difference(before, after)
+	result.Leftover = difference(after, before)
+
+	if err := m.exec(ctx, tx, up); err != nil {
+		result.Error = fmt.Sprintf("reapply up: %v", err)
+		return true, nil
+	}
+	return false, nil
+}
+
+// snapshotQueries lists information_schema queries describing schema objects as single text column.
+var snapshotQueries = map[Dialect][]string{
+	DialectPostgres: {
+		`SELECT 'table ' || table_schema || '.' || table_name || ' ' || table_type
+			FROM information_schema.tables WHERE table_schema NOT IN ('pg_catalog', 'information_schema');`,
+		`SELECT 'column ' || table_schema || '.' || table_name || '.' || column_name || ' ' ||
+			data_type || ' ' || is_nullable || ' ' || COALESCE(column_default, '')
+			FROM information_schema.columns WHERE table_schema NOT IN ('pg_catalog', 'information_schema');`,
+		`SELECT 'constraint ' || table_schema || '.' || table_name || '.' || constraint_name || ' ' || constraint_type
+			FROM information_schema.table_constraints WHERE table_schema NOT IN ('pg_catalog', 'information_schema');`,
+		`SELECT 'index ' || schemaname || '.' || indexname || ' ' || indexdef
+			FROM pg_indexes WHERE schemaname NOT IN ('pg_catalog', 'information_schema');`,
+		`SELECT 'sequence ' || sequence_schema || '.' || sequence_name
+			FROM information_schema.sequences WHERE sequence_schema NOT IN ('pg_catalog', 'information_schema');`,
+		`SELECT 'routine ' || routine_schema || '.' || specific_name
+			FROM information_schema.routines WHERE routine_schema NOT IN ('pg_catalog', 'information_schema');`,
+	},
+	DialectMySQL: {
+		`SELECT CONCAT('table ', table_name, ' ', table_type)
+			FROM information_schema.tables WHERE table_schema = DATABASE();`,
+		`SELECT CONCAT('column ', table_name, '.', column_name, ' ', column_type, ' ', is_nullable, ' ', COALESCE(column_default, ''))
+			FROM information_schema.columns WHERE table_schema = DATABASE();`,
+		`SELECT CONCAT('constraint ', table_name, '.', constraint_name, ' ', constraint_type)
+			FROM information_schema.table_constraints WHERE constraint_schema = DATABASE();`,
+		`SELECT CONCAT('index ', table_name, '.', index_name, ' ', seq_in_index, ' ', COALESCE(column_name, ''), ' ', non_unique)
+			FROM information_schema.statistics WHERE table_schema = DATABASE();`,
+		`SELECT CONCAT('routine ', routine_type, ' ', routine_name)
+			FROM information_schema.routines WHERE routine_schema = DATABASE();`,
+	},
+}
+
+// snapshot describes the current schema objects of the dialect, sorted.
+func snapshot(ctx context.Context, tx ExecutableScanner, dialect Dialect) ([]string, error) {
+	queries, ok := snapshotQueries[dialect]
+	if !ok {
+		return nil, ErrUnsupportedDialect
+	}
+
+	result := make([]string, 0)
+	for _, query := range queries {
+		rows, err := tx.Scan(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("capture schema: %w", err)
+		}
+
+		for rows.Next() {
+			var object string
+			if err := rows.Scan(&object); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("capture schema: %w", err)
+			}
+			result = append(result, object)
+		}
+
+		err = rowsErr(rows)
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("capture schema: %w", err)
+		}
+	}
+	slices.Sort(result)
+	return result, nil
+}
+
+// difference returns the sorted elements of a missing in b.
+func difference(a, b []string) []string {
+	result := make([]string, 0)
+	for _, item := range a {
+		if _, found := slices.BinarySearch(b, item); !found {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -2,10 +2,38 @@ package migration
 
 import (
 	"strings"
+	"time"
 )
 
 type Option func(*migration)
 
+// TransactionMode defines how migration steps are grouped into transactions.
+type TransactionMode int
+
+const (
+	// TransactionSingle runs all steps in one transaction.
+	TransactionSingle TransactionMode = iota
+
+	// TransactionPerStage runs the steps of each stage in a separate transaction.
+	TransactionPerStage
+
+	// TransactionPerStep runs each step (a file in a stage) in a separate transaction.
+	// Stages run one after another, so the stages of a file are committed separately.
+	TransactionPerStep
+)
+
+// shares reports whether two consecutive steps run in the same transaction.
+func (mode TransactionMode) shares(prev, next Step) bool {
+	switch mode {
+	case TransactionPerStage:
+		return prev.Stage == next.Stage
+	case TransactionPerStep:
+		return false
+	default:
+		return true
+	}
+}
+
 // WithRoot sets the root directory for migration files.
 func WithRoot(root string) Option {
 	root = normalizePath(root)
@@ -36,3 +64,75 @@ func WithEnv(isDev bool) Option {
 		q.dev = isDev
 	}
 }
+
+// WithTable sets the table used to track applied migrations, optionally schema qualified (e.g. "schema.table").
+// Migrations using different tables are tracked and locked independently.
+func WithTable(name string) Option {
+	name = strings.TrimSpace(name)
+	return func(q *migration) {
+		if name != "" {
+			q.table = name
+		}
+	}
+}
+
+// WithTransactionMode sets how migration steps are grouped into transactions.
+// Defaults to TransactionSingle.
+func WithTransactionMode(mode TransactionMode) Option {
+	return func(q *migration) {
+		q.txMode = mode
+	}
+}
+
+// WithLegacyNames identifies migration files by name only, ignoring their directory relative to root.
+// Enable it for tables created by versions where nested files were recorded without directory.
+func WithLegacyNames(enabled bool) Option {
+	return func(q *migration) {
+		q.legacyNames = enabled
+	}
+}
+
+// WithTemplateData renders migration files as text/template with data (e.g. {{ .Schema }}) on Load.
+// Missing variables fail Load instead of the migration run.
+func WithTemplateData(data any) Option {
+	return func(q *migration) {
+		q.data = data
+	}
+}
+
+// WithVersion sets the application version label recorded with applied migrations.
+func WithVersion(version string) Option {
+	version = strings.TrimSpace(version)
+	return func(q *migration) {
+		q.version = version
+	}
+}
+
+// WithLockTimeout sets the maximum time to wait for the migration lock held by other processes.
+// Up, Down and Refresh fail with ErrLockTimeout when the lock isn't acquired in time.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(q *migration) {
+		if timeout >= 0 {
+			q.lockTimeout = timeout
+		}
+	}
+}
+
+// WithFormat reads migration files in another migration tool layout (e.g. GooseFormat("main")) on Load.
+// Files not matching the format are ignored.
+func WithFormat(format FileFormat) Option {
+	return func(q *migration) {
+		q.format = format
+	}
+}
+
+// WithListener registers listeners notified of migration lifecycle events.
+func WithListener(listeners ...EventListener) Option {
+	return func(q *migration) {
+		for _, listener := range listeners {
+			if listener != nil {
+				q.listeners = append(q.listeners, listener)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package migration
+
+import "fmt"
+
+// StatementError reports the failed statement of a migration script.
+type StatementError struct {
+	Line      int // Line of the statement in the migration file.
+	Statement string
+	Err       error
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// MigrationError reports the file and stage of a failed migration step.
+type MigrationError struct {
+	Name      string
+	Stage     string
+	Direction Direction
+	Err       error
+}
+
+func newMigrationError(step Step, err error) *MigrationError {
+	return &MigrationError{
+		Name:      step.Name,
+		Stage:     step.Stage,
+		Direction: step.Direction,
+		Err:       err,
+	}
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf(`%s "%s" on %s stage: %v`, e.Direction, e.Name, e.Stage, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
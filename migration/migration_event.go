@@ -0,0 +1,45 @@
+package migration
+
+import (
+	"context"
+	"time"
+)
+
+// EventListener receives migration lifecycle events of Up, Down and Refresh runs, e.g. for logs,
+// metrics or notifications. Listeners are called synchronously, in registration order.
+// Dry-runs and runs with nothing to apply emit no event.
+type EventListener interface {
+	// BeforeAll is called with the execution plan after the migration lock is acquired.
+	BeforeAll(ctx context.Context, plan Plan)
+
+	// BeforeFile is called before a step runs.
+	BeforeFile(ctx context.Context, step Step)
+
+	// AfterFile is called after a step runs and is committed.
+	// Steps sharing a transaction are reported together once the transaction commits.
+	AfterFile(ctx context.Context, step Step, duration time.Duration)
+
+	// OnError is called when a step fails.
+	OnError(ctx context.Context, step Step, duration time.Duration, err error)
+
+	// AfterAll is called with the committed entries and the total duration once the run finishes.
+	// err is the run error, nil on success.
+	AfterAll(ctx context.Context, result Summary, duration time.Duration, err error)
+}
+
+// NopListener implements EventListener with no-op callbacks.
+// Embed it to implement only the needed callbacks.
+type NopListener struct{}
+
+func (NopListener) BeforeAll(context.Context, Plan)                         {}
+func (NopListener) BeforeFile(context.Context, Step)                        {}
+func (NopListener) AfterFile(context.Context, Step, time.Duration)          {}
+func (NopListener) OnError(context.Context, Step, time.Duration, error)     {}
+func (NopListener) AfterAll(context.Context, Summary, time.Duration, error) {}
+
+// emit calls the callback for each registered listener.
+func (m *migration) emit(callback func(EventListener)) {
+	for _, listener := range m.listeners {
+		callback(listener)
+	}
+}
@@ -0,0 +1,139 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// LintRule identifies a check reported by Lint.
+type LintRule string
+
+const (
+	LintIgnoredFile        LintRule = "ignored-file"        // File name not matching "timestamp-name.ext", skipped by Load.
+	LintDuplicateTimestamp LintRule = "duplicate-timestamp" // Several files sharing a timestamp.
+	LintUnknownStage       LintRule = "unknown-stage"       // Stage not in the expected stages, skipped when no stage is passed.
+	LintEmptyUp            LintRule = "empty-up"            // Up section without script.
+	LintMissingDown        LintRule = "missing-down"        // Up section without down section.
+	LintOutOfOrder         LintRule = "out-of-order"        // Pending file older than the latest applied file.
+)
+
+// LintIssue describes a problem found by Lint.
+type LintIssue struct {
+	Rule    LintRule `json:"rule" yaml:"rule"`
+	Name    string   `json:"name" yaml:"name"`   // Migration name, or file path for ignored files.
+	Stage   string   `json:"stage" yaml:"stage"` // Stage of the issue, empty for file level issues.
+	Message string   `json:"message" yaml:"message"`
+}
+
+func (i LintIssue) String() string {
+	if i.Stage == "" {
+		return fmt.Sprintf("%s: %s", i.Name, i.Message)
+	}
+	return fmt.Sprintf("%s (%s): %s", i.Name, i.Stage, i.Message)
+}
+
+func (m *migration) Lint(stages ...string) ([]LintIssue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return m.LintContext(ctx, stages...)
+}
+
+func (m *migration) LintContext(ctx context.Context, stages ...string) ([]LintIssue, error) {
+	// Hot reload on dev mode
+	if m.dev {
+		if err := m.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	result := make([]LintIssue, 0)
+	for _, path := range m.ignored {
+		result = append(result, LintIssue{
+			Rule:    LintIgnoredFile,
+			Name:    path,
+			Message: "file name doesn't match the migration layout and is ignored",
+		})
+	}
+
+	// Group files by timestamp
+	timestamps := make(map[int64][]string)
+	for _, file := range m.files {
+		if len(file.Stages()) > 0 {
+			timestamps[file.timestamp] = append(timestamps[file.timestamp], file.name)
+		}
+	}
+
+	migrated, err := m.SummaryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve latest applied file
+	var latest int64
+	var latestName string
+	for _, file := range m.files {
+		for _, stage := range file.Stages() {
+			if migrated.includes(file.name, stage) && file.timestamp >= latest {
+				latest, latestName = file.timestamp, file.name
+			}
+		}
+	}
+
+	for _, file := range m.files {
+		if names := timestamps[file.timestamp]; len(names) > 1 && names[0] == file.name {
+			result = append(result, LintIssue{
+				Rule:    LintDuplicateTimestamp,
+				Name:    file.name,
+				Message: fmt.Sprintf("timestamp %d shared with %v", file.timestamp, names[1:]),
+			})
+		}
+
+		pending := false
+		for _, stage := range file.Stages() {
+			if len(stages) > 0 && !slices.Contains(stages, stage) {
+				result = append(result, LintIssue{
+					Rule:    LintUnknownStage,
+					Name:    file.name,
+					Stage:   stage,
+					Message: fmt.Sprintf("stage not in %v", stages),
+				})
+			}
+
+			if _, ok := file.UpStep(stage); !ok {
+				result = append(result, LintIssue{
+					Rule:    LintEmptyUp,
+					Name:    file.name,
+					Stage:   stage,
+					Message: "up section is empty",
+				})
+			}
+
+			if _, ok := file.DownStep(stage); !ok {
+				result = append(result, LintIssue{
+					Rule:    LintMissingDown,
+					Name:    file.name,
+					Stage:   stage,
+					Message: "down section is missing",
+				})
+			}
+
+			if !migrated.includes(file.name, stage) {
+				pending = true
+			}
+		}
+
+		if pending && file.timestamp < latest {
+			result = append(result, LintIssue{
+				Rule:    LintOutOfOrder,
+				Name:    file.name,
+				Message: fmt.Sprintf("pending file is older than applied %q", latestName),
+			})
+		}
+	}
+	return result, nil
+}
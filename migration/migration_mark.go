@@ -0,0 +1,149 @@
+package migration
+
+import (
+	"context"
+	"time"
+)
+
+func (m *migration) Mark(stages []string, options ...MigrationOption) (Summary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return m.MarkContext(ctx, stages, options...)
+}
+
+func (m *migration) MarkContext(ctx context.Context, stages []string, options ...MigrationOption) (Summary, error) {
+	return m.mark(ctx, stages, false, options...)
+}
+
+func (m *migration) Unmark(stages []string, options ...MigrationOption) (Summary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return m.UnmarkContext(ctx, stages, options...)
+}
+
+func (m *migration) UnmarkContext(ctx context.Context, stages []string, options ...MigrationOption) (Summary, error) {
+	return m.mark(ctx, stages, true, options...)
+}
+
+// mark records pending steps as applied, or removes applied entries on unmark, without running scripts.
+func (m *migration) mark(ctx context.Context, stages []string, unmark bool, options ...MigrationOption) (Summary, error) {
+	if len(stages) == 0 {
+		return nil, nil
+	}
+
+	// Hot reload on dev mode
+	if m.dev {
+		if err := m.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	// Create option
+	option := newOption()
+	for _, opt := range options {
+		opt(option)
+	}
+
+	// Acquire lock unless dry-run
+	if option.plan == nil {
+		unlock, err := m.lock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	migrated, err := m.SummaryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan Plan
+	if unmark {
+		plan = m.unmarkPlan(stages, migrated, option)
+	} else if plan, err = m.plan(actionUp, stages, migrated, option); err != nil {
+		return nil, err
+	}
+
+	if option.plan != nil {
+		*option.plan = plan
+		direction := DirectionUp
+		if unmark {
+			direction = DirectionDown
+		}
+		return plan.summary(direction, migrated.LastBatch()+1), nil
+	}
+
+	if plan.IsEmpty() {
+		return nil, nil
+	}
+
+	// Record steps
+	result := make(Summary, 0)
+	batch := migrated.LastBatch() + 1
+	err = m.db.Transaction(ctx, func(tx ExecutableScanner) error {
+		for _, step := range plan {
+			item, err := m.record(ctx, tx, step, batch, 0)
+			if err != nil {
+				return newMigrationError(step, err)
+			}
+			result = append(result, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// unmarkPlan resolves applied entries of the stages to remove, latest first.
+// Entries without migration file (orphaned) are matched by name.
+func (m *migration) unmarkPlan(stages []string, migrated Summary, option *migrationOption) Plan {
+	only := option.only.Elements()
+	exclude := option.exclude.Elements()
+	files := m.files.Filter(only, exclude)
+
+	result := make(Plan, 0)
+	for _, stage := range stages {
+		for i := len(migrated) - 1; i >= 0; i-- {
+			item := migrated[i]
+			if item.Stage != stage {
+				continue
+			}
+
+			if file, ok := m.files.Find(item.Name); ok {
+				if _, ok := files.Find(file.name); !ok {
+					continue
+				}
+				if option.target != nil && file.timestamp <= *option.target {
+					continue
+				}
+			} else if orphan := (migrationFile{name: item.Name}); (len(only) > 0 && !orphan.Match(only...)) || orphan.Match(exclude...) {
+				continue
+			}
+
+			result = append(result, Step{
+				Name:       item.Name,
+				Stage:      item.Stage,
+				Direction:  DirectionDown,
+				Repeatable: item.Repeatable,
+			})
+		}
+	}
+
+	// Limit to the latest entries
+	if option.steps > 0 {
+		selected := make([]string, 0)
+		for _, name := range result.Names() {
+			if len(selected) < option.steps {
+				selected = append(selected, name)
+			}
+		}
+		result = result.only(selected)
+	}
+	return result
+}
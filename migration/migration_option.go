@@ -3,6 +3,11 @@ package migration
 type migrationOption struct {
 	only    *optionSet
 	exclude *optionSet
+	plan    *Plan
+	steps   int
+	target  *int64
+	batches int
+	scratch MigrationSource
 }
 
 func newOption() *migrationOption {
@@ -27,3 +32,48 @@ func SkipFiles(files ...string) MigrationOption {
 		o.exclude.Add(files...)
 	}
 }
+
+// DryRun resolves the scripts to run into plan without touching the database.
+// The returned summary lists the migrations that would be applied or rolled back.
+func DryRun(plan *Plan) MigrationOption {
+	return func(o *migrationOption) {
+		o.plan = plan
+	}
+}
+
+// Steps limits the migration to n files. Up applies the first n pending files,
+// Down and Refresh roll back the last n applied files.
+func Steps(n int) MigrationOption {
+	return func(o *migrationOption) {
+		if n > 0 {
+			o.steps = n
+		}
+	}
+}
+
+// Target limits the migration by file timestamp. Up applies files up to and including
+// the timestamp, Down and Refresh roll back files after the timestamp.
+func Target(timestamp int64) MigrationOption {
+	return func(o *migrationOption) {
+		o.target = &timestamp
+	}
+}
+
+// Batches limits Down and Refresh to the files applied in the last n batches.
+// Every Up or Refresh run records its files under a new batch number.
+func Batches(n int) MigrationOption {
+	return func(o *migrationOption) {
+		if n > 0 {
+			o.batches = n
+		}
+	}
+}
+
+// Scratch runs CheckReversible on a disposable database of the same dialect instead of the migration source,
+// e.g. a copy of the schema. Required on MySQL, where DDL statements commit implicitly and can't be rolled back.
+// Pending files are resolved from the migration source history.
+func Scratch(source MigrationSource) MigrationOption {
+	return func(o *migrationOption) {
+		o.scratch = source
+	}
+}
@@ -0,0 +1,162 @@
+package migration
+
+import (
+	"slices"
+	"time"
+)
+
+// Direction indicates whether a step applies or rolls back a migration.
+type Direction string
+
+const (
+	DirectionUp   Direction = "up"
+	DirectionDown Direction = "down"
+)
+
+// Step describes a single migration script scheduled to run.
+type Step struct {
+	Name          string    `json:"name" yaml:"name"`
+	Stage         string    `json:"stage" yaml:"stage"`
+	Direction     Direction `json:"direction" yaml:"direction"`
+	Script        string    `json:"script" yaml:"script"`
+	NoTransaction bool      `json:"no_transaction" yaml:"no_transaction"` // Runs outside of transactions.
+	Repeatable    bool      `json:"repeatable" yaml:"repeatable"`         // Re-runs whenever the script changes.
+	Baseline      bool      `json:"baseline" yaml:"baseline"`             // Recorded without running, the squashed files are already applied.
+
+	fn        MigrationFunc
+	timestamp int64    // Timestamp of the step file, identifies the file along with the name.
+	lines     []int    // File line number of each script line.
+	replaces  []string // Squashed entries replaced by the baseline entry.
+}
+
+// IsFunc reports whether the step runs a registered Go function instead of a script.
+func (s Step) IsFunc() bool {
+	return s.fn != nil
+}
+
+// fileLine maps a 1-based script line to its line in the migration file.
+func (s Step) fileLine(line int) int {
+	if line > 0 && line <= len(s.lines) {
+		return s.lines[line-1]
+	}
+	return line
+}
+
+// migrated returns the summary entry for the step.
+func (s Step) migrated(batch int) Migrated {
+	item := Migrated{
+		Name:       s.Name,
+		Stage:      s.Stage,
+		CreatedAt:  time.Now(),
+		Repeatable: s.Repeatable,
+	}
+	if s.Direction == DirectionUp {
+		item.Batch = batch
+		if !s.IsFunc() {
+			item.Checksum = checksum(s.Script)
+		}
+	}
+	return item
+}
+
+// Plan is the ordered list of steps run by Up, Down or Refresh.
+type Plan []Step
+
+func (p Plan) IsEmpty() bool {
+	return len(p) == 0
+}
+
+func (p Plan) GroupByStage() map[string][]Step {
+	result := make(map[string][]Step)
+	for _, step := range p {
+		result[step.Stage] = append(result[step.Stage], step)
+	}
+	return result
+}
+
+// Names returns the distinct file names in the plan.
+func (p Plan) Names() []string {
+	result := make([]string, 0)
+	for _, step := range p {
+		if !slices.Contains(result, step.Name) {
+			result = append(result, step.Name)
+		}
+	}
+	return result
+}
+
+// summary returns the summary entries of steps in the given direction.
+func (p Plan) summary(direction Direction, batch int) Summary {
+	result := make(Summary, 0)
+	for _, step := range p {
+		if step.Direction == direction {
+			result = append(result, step.migrated(batch))
+		}
+	}
+	return result
+}
+
+// of reports whether the step belongs to the file.
+func (s Step) of(file migrationFile) bool {
+	return s.timestamp == file.timestamp && s.Name == file.name
+}
+
+// limit returns the versioned steps of the given files.
+// Repeatable steps run after the versioned steps of their stage, so they are kept for stages
+// with selected versioned steps, or for stages without versioned steps when none is left out.
+func (p Plan) limit(files sortableFiles) Plan {
+	selected := make(map[string]bool)
+	skipped := false
+	for _, step := range p {
+		if step.Repeatable {
+			continue
+		} else if slices.ContainsFunc(files, step.of) {
+			selected[step.Stage] = true
+		} else {
+			skipped = true
+		}
+	}
+
+	result := make(Plan, 0)
+	for _, step := range p {
+		if step.Repeatable && (selected[step.Stage] || !skipped) {
+			result = append(result, step)
+		} else if !step.Repeatable && slices.ContainsFunc(files, step.of) {
+			result = append(result, step)
+		}
+	}
+	return result
+}
+
+// only returns the steps of the given files.
+func (p Plan) only(names []string) Plan {
+	result := make(Plan, 0)
+	for _, step := range p {
+		if slices.Contains(names, step.Name) {
+			result = append(result, step)
+		}
+	}
+	return result
+}
+
+// action identifies the operation being planned.
+type action string
+
+const (
+	actionUp      action = "up"
+	actionDown    action = "down"
+	actionRefresh action = "refresh"
+)
+
+// forward reports whether the action selects files in ascending timestamp order.
+func (a action) forward() bool {
+	return a == actionUp
+}
+
+// reports returns the direction of steps reported in the action summary.
+func (a action) reports() Direction {
+	if a == actionDown {
+		return DirectionDown
+	}
+	return DirectionUp
+}
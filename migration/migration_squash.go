@@ -0,0 +1,196 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SquashResult describes the files written and removed by Squash.
+type SquashResult struct {
+	Files    []string `json:"files" yaml:"files"`       // Written baseline files.
+	Squashed []string `json:"squashed" yaml:"squashed"` // Removed migration files, relative to root.
+}
+
+// squashSource is a migration file selected for squash.
+type squashSource struct {
+	path string
+	name string // Migration name, as recorded in the migrations table.
+	file *migrationFile
+}
+
+func (m *migration) Squash(to int64, root string, options ...MigrationOption) (SquashResult, error) {
+	result := SquashResult{Files: make([]string, 0), Squashed: make([]string, 0)}
+	if root == "" {
+		return result, errors.New("root parameter is required")
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.format != nil {
+		return result, errors.New("squash supports native migration files only")
+	}
+
+	for _, fn := range m.funcs {
+		if fn.timestamp <= to {
+			return result, fmt.Errorf(`"%s" is a Go migration older than %d and can not be squashed`, fn.name, to)
+		}
+	}
+
+	// Create option
+	option := newOption()
+	for _, opt := range options {
+		opt(option)
+	}
+
+	// Read raw files, templates are kept as is
+	paths, err := m.fs.Lookup(m.root, `.*\.`+regexp.QuoteMeta(m.ext))
+	if err != nil {
+		return result, err
+	}
+
+	sources := make([]squashSource, 0)
+	for _, path := range paths {
+		content, err := m.fs.ReadFile(path)
+		if err != nil {
+			return result, err
+		}
+
+		file := newMigrationFile(path, string(content))
+		if file == nil || file.timestamp == 0 || file.timestamp > to {
+			continue
+		}
+		if len(file.repeats) > 0 {
+			return result, fmt.Errorf(`"%s" has repeatable sections and can not be squashed`, path)
+		}
+
+		// Baselines must list the name as recorded
+		name := m.fileName(path, file.name)
+		directive := fmt.Sprintf("-- { squashes: %s }", name)
+		if names := parseDirectiveValues(directive, "squashes"); len(names) != 1 || names[0] != name {
+			return result, fmt.Errorf(`"%s" name can not be listed in the baseline squashes directive`, path)
+		}
+		sources = append(sources, squashSource{path: path, name: name, file: file})
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		if sources[i].file.timestamp == sources[j].file.timestamp {
+			return sources[i].path < sources[j].path
+		}
+		return sources[i].file.timestamp < sources[j].file.timestamp
+	})
+
+	// Combine scripts per stage
+	stages := make([]string, 0)
+	for _, source := range sources {
+		for _, stage := range source.file.stages {
+			if !slices.Contains(stages, stage) {
+				stages = append(stages, stage)
+			}
+		}
+	}
+
+	// Baselines of stages get distinct timestamps, the last one is to,
+	// and names including to, so earlier baselines are squashed as listed files
+	names := make([]string, 0, len(stages))
+	for i, stage := range stages {
+		name := fmt.Sprintf(
+			"%d-%s.%s",
+			to-int64(len(stages)-1-i), slugify("baseline", strconv.FormatInt(to, 10), stage), m.ext,
+		)
+		for _, source := range sources {
+			if filepath.Base(source.path) == name {
+				return result, fmt.Errorf(`"%s" already squashed to %d`, source.path, to)
+			}
+		}
+		names = append(names, name)
+	}
+
+	plan := make(Plan, 0)
+	for i, stage := range stages {
+		ups := make([]string, 0)
+		downs := make([]string, 0)
+		squashes := make([]string, 0)
+		reversible := true
+		transactional := true
+		for _, source := range sources {
+			script, ok := source.file.UpScript(stage)
+			if !ok {
+				continue
+			}
+
+			// Files without up script are never recorded
+			if script != "" {
+				ups = append(ups, fmt.Sprintf("-- %s\n%s", source.file.name, script))
+				squashes = append(squashes, source.name)
+			}
+			if down, ok := source.file.DownScript(stage); ok {
+				if down != "" {
+					downs = append([]string{fmt.Sprintf("-- %s\n%s", source.file.name, down)}, downs...)
+				}
+			} else {
+				reversible = false
+			}
+			transactional = transactional && source.file.Transactional()
+		}
+
+		name := names[i]
+		fileOptions := []string{"baseline"}
+		if !transactional {
+			fileOptions = append(fileOptions, "no-transaction")
+		}
+
+		up := map[string]string{stage: strings.Join(ups, "\n\n")}
+		down := map[string]string{}
+		if reversible {
+			down[stage] = strings.Join(downs, "\n\n")
+		}
+
+		_, stepName, _, _ := parseFileName(name)
+		plan = append(plan, Step{
+			Name:          stepName,
+			Stage:         stage,
+			Direction:     DirectionUp,
+			Script:        up[stage],
+			NoTransaction: !transactional,
+		})
+		result.Files = append(result.Files, name)
+
+		if option.plan == nil {
+			if err := writeMigrationFile(root, name, []string{stage}, up, down, fileOptions, squashes); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	// Remove squashed files
+	for _, source := range sources {
+		rel, err := filepath.Rel(m.root, source.path)
+		if err != nil {
+			return result, err
+		}
+
+		rel = filepath.ToSlash(rel)
+		if slices.Contains(result.Files, rel) {
+			continue
+		}
+
+		if option.plan == nil {
+			if err := os.Remove(normalizePath(root, rel)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return result, err
+			}
+		}
+		result.Squashed = append(result.Squashed, rel)
+	}
+
+	if option.plan != nil {
+		*option.plan = plan
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package migration
+
+import "time"
+
+// State describes the state of a migration stage compared to the migrations table.
+type State string
+
+const (
+	StateApplied  State = "applied"  // Recorded and present in files.
+	StatePending  State = "pending"  // Present in files but not recorded.
+	StateOrphaned State = "orphaned" // Recorded but missing from files.
+)
+
+// StatusEntry represents the state of a file in a stage.
+type StatusEntry struct {
+	Name      string    `json:"name" yaml:"name"`
+	Stage     string    `json:"stage" yaml:"stage"`
+	State     State     `json:"state" yaml:"state"`
+	AppliedAt time.Time `json:"applied_at" yaml:"applied_at"`
+}
+
+// StatusReport lists the state of every file and stage.
+type StatusReport []StatusEntry
+
+func (r StatusReport) IsEmpty() bool {
+	return len(r) == 0
+}
+
+// Stages returns the stages in the order they appear in the report.
+func (r StatusReport) Stages() []string {
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, entry := range r {
+		if !seen[entry.Stage] {
+			seen[entry.Stage] = true
+			result = append(result, entry.Stage)
+		}
+	}
+	return result
+}
+
+func (r StatusReport) GroupByStage() map[string]StatusReport {
+	result := make(map[string]StatusReport)
+	for _, entry := range r {
+		result[entry.Stage] = append(result[entry.Stage], entry)
+	}
+	return result
+}
+
+func (r StatusReport) Applied() StatusReport {
+	return r.filter(StateApplied)
+}
+
+func (r StatusReport) Pending() StatusReport {
+	return r.filter(StatePending)
+}
+
+func (r StatusReport) Orphaned() StatusReport {
+	return r.filter(StateOrphaned)
+}
+
+func (r StatusReport) filter(state State) StatusReport {
+	result := make(StatusReport, 0)
+	for _, entry := range r {
+		if entry.State == state {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
@@ -1,11 +1,61 @@
 package migration
 
-import "time"
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
 
 type Migrated struct {
-	Name      string    `db:"name"`
-	Stage     string    `db:"stage"`
-	CreatedAt time.Time `db:"created_at"`
+	Name       string        `db:"name" json:"name" yaml:"name"`
+	Stage      string        `db:"stage" json:"stage" yaml:"stage"`
+	CreatedAt  time.Time     `db:"created_at" json:"created_at" yaml:"created_at"`
+	Checksum   string        `db:"checksum" json:"checksum" yaml:"checksum"`
+	Batch      int           `db:"batch" json:"batch" yaml:"batch"`
+	Duration   time.Duration `db:"duration" json:"-" yaml:"-"`                     // Execution time, stored and serialized (duration_ms) in milliseconds.
+	AppliedBy  string        `db:"applied_by" json:"applied_by" yaml:"applied_by"` // Operating system user applied the migration.
+	Host       string        `db:"host" json:"host" yaml:"host"`                   // Host name applied the migration.
+	Version    string        `db:"version" json:"version" yaml:"version"`          // Application version label set by WithVersion.
+	Repeatable bool          `db:"kind" json:"repeatable" yaml:"repeatable"`       // Repeatable migration, stored as "repeatable" kind.
+}
+
+// migratedDocument is the json and yaml form of Migrated, with the duration in milliseconds.
+type migratedDocument struct {
+	migratedFields `yaml:",inline"`
+	DurationMS     int64 `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// migratedFields has the Migrated fields without its marshal methods.
+type migratedFields Migrated
+
+func (m Migrated) MarshalJSON() ([]byte, error) {
+	return json.Marshal(migratedDocument{migratedFields(m), m.Duration.Milliseconds()})
+}
+
+func (m *Migrated) UnmarshalJSON(data []byte) error {
+	var doc migratedDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*m = Migrated(doc.migratedFields)
+	m.Duration = time.Duration(doc.DurationMS) * time.Millisecond
+	return nil
+}
+
+func (m Migrated) MarshalYAML() (any, error) {
+	return migratedDocument{migratedFields(m), m.Duration.Milliseconds()}, nil
+}
+
+func (m *Migrated) UnmarshalYAML(value *yaml.Node) error {
+	var doc migratedDocument
+	if err := value.Decode(&doc); err != nil {
+		return err
+	}
+	*m = Migrated(doc.migratedFields)
+	m.Duration = time.Duration(doc.DurationMS) * time.Millisecond
+	return nil
 }
 
 type Summary []Migrated
@@ -51,12 +101,70 @@ func (s Summary) ForStage(stage string) Summary {
 	return result
 }
 
+// LastBatch returns the highest recorded batch number.
+func (s Summary) LastBatch() int {
+	result := 0
+	for _, item := range s {
+		result = max(result, item.Batch)
+	}
+	return result
+}
+
+// Batches returns the distinct batch numbers in descending order.
+func (s Summary) Batches() []int {
+	result := make([]int, 0)
+	for _, item := range s {
+		if !slices.Contains(result, item.Batch) {
+			result = append(result, item.Batch)
+		}
+	}
+	slices.Sort(result)
+	slices.Reverse(result)
+	return result
+}
+
 func (s Summary) includes(name, stage string) bool {
+	_, ok := s.find(name, stage)
+	return ok
+}
+
+// find returns the versioned entry of the file stage.
+func (s Summary) find(name, stage string) (Migrated, bool) {
+	for _, item := range s {
+		if item.Name == name && item.Stage == stage && !item.Repeatable {
+			return item, true
+		}
+	}
+
+	return Migrated{}, false
+}
+
+// findRepeatable returns the repeatable entry of the file stage.
+func (s Summary) findRepeatable(name, stage string) (Migrated, bool) {
 	for _, item := range s {
-		if item.Name == name && item.Stage == stage {
-			return true
+		if item.Name == name && item.Stage == stage && item.Repeatable {
+			return item, true
 		}
 	}
 
-	return false
+	return Migrated{}, false
+}
+
+// DriftState describes how an applied migration differs from its file.
+type DriftState string
+
+const (
+	DriftChanged DriftState = "changed" // Up script changed after being applied.
+	DriftRemoved DriftState = "removed" // File or stage section removed after being applied.
+	DriftUnknown DriftState = "unknown" // Applied without checksum (before checksums were tracked).
+)
+
+// Drift describes an applied migration whose up script changed after it was applied.
+type Drift struct {
+	Name     string     `json:"name" yaml:"name"`
+	Stage    string     `json:"stage" yaml:"stage"`
+	State    DriftState `json:"state" yaml:"state"`
+	Applied  string     `json:"applied" yaml:"applied"` // Checksum stored when the migration was applied.
+	Current  string     `json:"current" yaml:"current"` // Checksum of the current up script, empty if removed.
+	Migrated time.Time  `json:"migrated" yaml:"migrated"`
 }
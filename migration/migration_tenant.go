@@ -0,0 +1,228 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/go-universal/fs"
+	"github.com/go-universal/sql/mysql"
+	"github.com/go-universal/sql/postgres"
+)
+
+// errTenantDryRun is returned for dry-runs in tenant mode, tenants would share the plan.
+var errTenantDryRun = errors.New("dry-run is not supported with tenants")
+
+// TenantResolver resolves the migration source of a tenant database.
+type TenantResolver func(ctx context.Context, tenant string) (MigrationSource, error)
+
+// TenantDiscovery lists the tenants migrated when no tenant is selected.
+type TenantDiscovery func(ctx context.Context) ([]string, error)
+
+// PostgresTenants resolves tenant sources from the connection manager, connecting on first use.
+func PostgresTenants(manager postgres.ConnectionManager) TenantResolver {
+	return func(ctx context.Context, tenant string) (MigrationSource, error) {
+		conn, err := manager.Resolve(ctx, tenant)
+		if err != nil {
+			return nil, err
+		}
+		return NewPostgresSource(conn), nil
+	}
+}
+
+// MySQLTenants resolves tenant sources from the connection manager, connecting on first use.
+func MySQLTenants(manager mysql.ConnectionManager) TenantResolver {
+	return func(ctx context.Context, tenant string) (MigrationSource, error) {
+		conn, err := manager.Resolve(ctx, tenant)
+		if err != nil {
+			return nil, err
+		}
+		return NewMySQLSource(conn), nil
+	}
+}
+
+// StaticTenants discovers a fixed tenant list.
+func StaticTenants(tenants ...string) TenantDiscovery {
+	return func(ctx context.Context) ([]string, error) {
+		return append([]string{}, tenants...), nil
+	}
+}
+
+// TenantResult is the migration result of a tenant.
+type TenantResult struct {
+	Tenant string
+	Result Summary // Entries committed before failure on error.
+	Err    error
+}
+
+// TenantReport lists tenant results in tenant order.
+type TenantReport []TenantResult
+
+// Failed returns the failed tenant results.
+func (r TenantReport) Failed() TenantReport {
+	result := make(TenantReport, 0)
+	for _, item := range r {
+		if item.Err != nil {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Err joins the tenant errors, nil if all tenants succeeded.
+func (r TenantReport) Err() error {
+	errs := make([]error, 0)
+	for _, item := range r.Failed() {
+		errs = append(errs, fmt.Errorf("tenant %q: %w", item.Tenant, item.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// TenantRunner applies migrations to the database of each tenant with bounded parallelism.
+// A failing tenant doesn't stop the others.
+type TenantRunner interface {
+	// Tenants returns the discovered tenants.
+	Tenants(ctx context.Context) ([]string, error)
+
+	// Up applies migration stages to the tenants, or all discovered tenants if empty.
+	// Returns an error only if tenants can't be discovered or DryRun is passed, tenant failures are reported per tenant.
+	Up(ctx context.Context, tenants, stages []string, options ...MigrationOption) (TenantReport, error)
+
+	// Down rolls back migration stages of the tenants, or all discovered tenants if empty.
+	// Returns an error only if tenants can't be discovered or DryRun is passed, tenant failures are reported per tenant.
+	Down(ctx context.Context, tenants, stages []string, options ...MigrationOption) (TenantReport, error)
+}
+
+type tenantRunner struct {
+	resolver    TenantResolver
+	discovery   TenantDiscovery
+	fs          fs.FlexibleFS
+	parallelism int
+	options     []Option
+}
+
+// TenantOption configures the tenant runner.
+type TenantOption func(*tenantRunner)
+
+// WithParallelism sets the number of tenants migrated concurrently. Defaults to 4.
+func WithParallelism(n int) TenantOption {
+	return func(r *tenantRunner) {
+		if n > 0 {
+			r.parallelism = n
+		}
+	}
+}
+
+// WithTenantOptions sets the migration options (e.g. WithRoot) used for each tenant.
+func WithTenantOptions(options ...Option) TenantOption {
+	return func(r *tenantRunner) {
+		r.options = append(r.options, options...)
+	}
+}
+
+// NewTenantRunner creates a runner migrating tenant databases resolved by the resolver
+// with migration files of the filesystem.
+func NewTenantRunner(resolver TenantResolver, discovery TenantDiscovery, fs fs.FlexibleFS, options ...TenantOption) TenantRunner {
+	runner := &tenantRunner{
+		resolver:    resolver,
+		discovery:   discovery,
+		fs:          fs,
+		parallelism: 4,
+		options:     make([]Option, 0),
+	}
+
+	for _, opt := range options {
+		opt(runner)
+	}
+	return runner
+}
+
+func (r *tenantRunner) Tenants(ctx context.Context) ([]string, error) {
+	if r.discovery == nil {
+		return nil, errors.New("tenant discovery not configured")
+	}
+	return r.discovery(ctx)
+}
+
+func (r *tenantRunner) Up(ctx context.Context, tenants, stages []string, options ...MigrationOption) (TenantReport, error) {
+	return r.run(ctx, tenants, options, func(ctx context.Context, m Migration) (Summary, error) {
+		return m.UpContext(ctx, stages, options...)
+	})
+}
+
+func (r *tenantRunner) Down(ctx context.Context, tenants, stages []string, options ...MigrationOption) (TenantReport, error) {
+	return r.run(ctx, tenants, options, func(ctx context.Context, m Migration) (Summary, error) {
+		return m.DownContext(ctx, stages, options...)
+	})
+}
+
+// run calls the action with the migration of each tenant concurrently.
+func (r *tenantRunner) run(ctx context.Context, tenants []string, options []MigrationOption, action func(context.Context, Migration) (Summary, error)) (TenantReport, error) {
+	// Tenants would write the plan concurrently
+	option := newOption()
+	for _, opt := range options {
+		opt(option)
+	}
+	if option.plan != nil {
+		return nil, errTenantDryRun
+	}
+
+	if len(tenants) == 0 {
+		discovered, err := r.Tenants(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tenants = discovered
+	}
+	unique := make([]string, 0, len(tenants))
+	for _, tenant := range tenants {
+		if !slices.Contains(unique, tenant) {
+			unique = append(unique, tenant)
+		}
+	}
+	tenants = unique
+
+	result := make(TenantReport, len(tenants))
+	semaphore := make(chan struct{}, r.parallelism)
+	var wg sync.WaitGroup
+	for i, tenant := range tenants {
+		result[i].Tenant = tenant
+
+		// Skip remaining tenants on cancellation
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			result[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(item *TenantResult) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			source, err := r.resolver(ctx, item.Tenant)
+			if err != nil {
+				item.Err = err
+				return
+			}
+
+			m, err := newMigration(source, r.fs, r.options...)
+			if err != nil {
+				item.Err = err
+				return
+			}
+
+			if err := m.InitializeContext(ctx); err != nil {
+				item.Err = err
+				return
+			}
+
+			item.Result, item.Err = action(ctx, m)
+		}(&result[i])
+	}
+	wg.Wait()
+	return result, nil
+}
@@ -0,0 +1,1554 @@
+package migration_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-universal/sql/migration"
+)
+
+type MockFS struct {
+	files map[string]string
+}
+
+func (*MockFS) Exists(path string) (bool, error)                        { return false, nil }
+func (*MockFS) Open(path string) (fs.File, error)                       { return nil, nil }
+func (*MockFS) Search(dir, phrase, ignore, ext string) (*string, error) { return nil, nil }
+func (*MockFS) Find(dir, pattern string) (*string, error)               { return nil, nil }
+func (*MockFS) FS() fs.FS                                               { return nil }
+func (*MockFS) Http() http.FileSystem                                   { return nil }
+func (f *MockFS) Lookup(dir, pattern string) ([]string, error) {
+	names := make([]string, 0)
+	for k := range f.files {
+		names = append(names, k)
+	}
+	return names, nil
+}
+func (f *MockFS) ReadFile(path string) ([]byte, error) {
+	v, ok := f.files[path]
+	if !ok {
+		return nil, errors.New("file not found")
+	}
+	return []byte(v), nil
+}
+
+// MockSource records executed statements and returns applied rows on scan.
+type MockSource struct {
+	applied    []migration.Migrated
+	statements []string
+	inserts    [][]any
+	fail       string
+	missing    []string           // Columns missing from the migrations table.
+	results    map[string][][]any // Rows returned for queries containing the key.
+	held       bool               // Lock is held by another process.
+	locks      []string           // Acquired lock keys.
+	created    int                // Executed CREATE TABLE IF NOT EXISTS statements.
+}
+
+func (s *MockSource) Transaction(ctx context.Context, cb func(migration.ExecutableScanner) error) error {
+	s.statements = append(s.statements, "BEGIN")
+	if err := cb(s); err != nil {
+		s.statements = append(s.statements, "ROLLBACK")
+		return err
+	}
+	s.statements = append(s.statements, "COMMIT")
+	return nil
+}
+
+func (s *MockSource) Exec(ctx context.Context, sql string, arguments ...any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.fail != "" && strings.Contains(sql, s.fail) {
+		return errors.New("mock failure")
+	}
+	if strings.HasPrefix(sql, "INSERT INTO") {
+		s.inserts = append(s.inserts, arguments)
+	}
+	if strings.HasPrefix(sql, "CREATE TABLE IF NOT EXISTS") {
+		s.created++
+	} else {
+		s.statements = append(s.statements, sql)
+	}
+	return nil
+}
+
+func (s *MockSource) Scan(ctx context.Context, sql string, arguments ...any) (migration.Rows, error) {
+	for key, values := range s.results {
+		if strings.Contains(sql, key) {
+			return &MockValueRows{values: values, index: -1}, nil
+		}
+	}
+	for _, column := range s.missing {
+		if strings.HasPrefix(sql, "SELECT "+column+" FROM") {
+			return nil, errors.New("unknown column " + column)
+		}
+	}
+	if !strings.Contains(sql, "ORDER BY") {
+		return &MockRows{}, nil
+	}
+	return &MockRows{items: s.applied, index: -1}, nil
+}
+
+func (s *MockSource) Lock(ctx context.Context, key string, timeout time.Duration) (func() error, error) {
+	if s.held {
+		return nil, fmt.Errorf("%w: %q is held by another process (waited %s)", migration.ErrLockTimeout, key, timeout)
+	}
+	s.locks = append(s.locks, key)
+	return func() error { return nil }, nil
+}
+
+// PlainSource hides the optional source interfaces of MockSource.
+type PlainSource struct {
+	mock *MockSource
+}
+
+func (s PlainSource) Transaction(ctx context.Context, cb func(migration.ExecutableScanner) error) error {
+	return s.mock.Transaction(ctx, cb)
+}
+
+func (s PlainSource) Exec(ctx context.Context, sql string, arguments ...any) error {
+	return s.mock.Exec(ctx, sql, arguments...)
+}
+
+func (s PlainSource) Scan(ctx context.Context, sql string, arguments ...any) (migration.Rows, error) {
+	return s.mock.Scan(ctx, sql, arguments...)
+}
+
+func (s *MockSource) QuoteIdentifier(name string) string {
+	return `"` + name + `"`
+}
+
+func (s *MockSource) Placeholder(index int) string {
+	return "$" + strconv.Itoa(index)
+}
+
+func (s *MockSource) Dialect() migration.Dialect {
+	return migration.DialectPostgres
+}
+
+// SchemaSource tracks tables created and dropped by statements and reports them on schema snapshots.
+type SchemaSource struct {
+	*MockSource
+	dialect migration.Dialect
+	tables  map[string]bool
+}
+
+func (s *SchemaSource) Transaction(ctx context.Context, cb func(migration.ExecutableScanner) error) error {
+	return s.MockSource.Transaction(ctx, func(migration.ExecutableScanner) error { return cb(s) })
+}
+
+func (s *SchemaSource) Exec(ctx context.Context, sql string, arguments ...any) error {
+	if name, ok := strings.CutPrefix(sql, "CREATE TABLE "); ok {
+		s.tables[strings.Fields(name)[0]] = true
+	} else if name, ok := strings.CutPrefix(sql, "DROP TABLE "); ok {
+		delete(s.tables, strings.Fields(name)[0])
+	}
+	return s.MockSource.Exec(ctx, sql, arguments...)
+}
+
+func (s *SchemaSource) Scan(ctx context.Context, sql string, arguments ...any) (migration.Rows, error) {
+	if !strings.Contains(sql, "information_schema.tables") {
+		return s.MockSource.Scan(ctx, sql, arguments...)
+	}
+
+	values := make([][]any, 0)
+	for name := range s.tables {
+		values = append(values, []any{"table " + name})
+	}
+	return &MockValueRows{values: values, index: -1}, nil
+}
+
+func (s *SchemaSource) Dialect() migration.Dialect {
+	return s.dialect
+}
+
+type MockRows struct {
+	items []migration.Migrated
+	index int
+}
+
+func (r *MockRows) Next() bool {
+	r.index++
+	return r.index < len(r.items)
+}
+
+func (r *MockRows) Scan(dest ...any) error {
+	item := r.items[r.index]
+	*dest[0].(*string) = item.Name
+	*dest[1].(*string) = item.Stage
+	*dest[2].(*time.Time) = item.CreatedAt
+	*dest[3].(**string) = &item.Checksum
+	batch := int64(item.Batch)
+	*dest[4].(**int64) = &batch
+	duration := item.Duration.Milliseconds()
+	*dest[5].(**int64) = &duration
+	*dest[6].(**string) = &item.AppliedBy
+	*dest[7].(**string) = &item.Host
+	*dest[8].(**string) = &item.Version
+	if item.Repeatable {
+		kind := "repeatable"
+		*dest[9].(**string) = &kind
+	}
+	return nil
+}
+
+func (r *MockRows) Close() {}
+
+// MockValueRows returns rows of values, scanned into destinations of the same or convertible type.
+type MockValueRows struct {
+	values [][]any
+	index  int
+}
+
+func (r *MockValueRows) Next() bool {
+	r.index++
+	return r.index < len(r.values)
+}
+
+func (r *MockValueRows) Scan(dest ...any) error {
+	for i, value := range r.values[r.index] {
+		target := reflect.ValueOf(dest[i]).Elem()
+		if value == nil {
+			target.SetZero()
+			continue
+		}
+
+		v := reflect.ValueOf(value)
+		if target.Kind() == reflect.Pointer {
+			ptr := reflect.New(target.Type().Elem())
+			ptr.Elem().Set(v.Convert(target.Type().Elem()))
+			target.Set(ptr)
+		} else {
+			target.Set(v.Convert(target.Type()))
+		}
+	}
+	return nil
+}
+
+func (r *MockValueRows) Close() {}
+
+// MockListener records lifecycle events.
+type MockListener struct {
+	migration.NopListener
+	events []string
+}
+
+func (l *MockListener) BeforeAll(ctx context.Context, plan migration.Plan) {
+	l.events = append(l.events, "before all "+strconv.Itoa(len(plan)))
+}
+
+func (l *MockListener) BeforeFile(ctx context.Context, step migration.Step) {
+	l.events = append(l.events, "before "+step.Stage+" "+step.Name)
+}
+
+func (l *MockListener) AfterFile(ctx context.Context, step migration.Step, duration time.Duration) {
+	l.events = append(l.events, "after "+step.Stage+" "+step.Name)
+}
+
+func (l *MockListener) OnError(ctx context.Context, step migration.Step, duration time.Duration, err error) {
+	l.events = append(l.events, "error "+step.Stage+" "+step.Name)
+}
+
+func (l *MockListener) AfterAll(ctx context.Context, result migration.Summary, duration time.Duration, err error) {
+	l.events = append(l.events, "after all "+strconv.Itoa(len(result))+" "+strconv.FormatBool(err != nil))
+}
+
+func newMockFS() *MockFS {
+	return &MockFS{
+		files: map[string]string{
+			"migrations/1741791024-create-users.sql": `
+-- { up: table }
+CREATE TABLE users (id INT);
+
+-- { down: table }
+DROP TABLE users;
+
+-- { up: index }
+CREATE INDEX users_id ON users (id);
+
+-- { down: index }
+DROP INDEX users_id;
+`,
+			"migrations/1741791025-users-email.sql": `
+-- { options: no-transaction }
+-- { up: index }
+CREATE INDEX CONCURRENTLY users_email ON users (email);
+`,
+			"migrations/invalid name.sql": `
+-- { up: table }
+CREATE TABLE ignored (id INT);
+`,
+		},
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	source := &MockSource{
+		applied: []migration.Migrated{{Name: "create users", Stage: "table"}},
+	}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	plan := make(migration.Plan, 0)
+	summary, err := mig.Up([]string{"table", "index"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	assert.Empty(t, source.statements)
+	assert.Equal(t, []string{"create users", "users email"}, summary.Names())
+
+	require.Len(t, plan, 2)
+	assert.Equal(t, "create users", plan[0].Name)
+	assert.Equal(t, "index", plan[0].Stage)
+	assert.Equal(t, migration.DirectionUp, plan[0].Direction)
+	assert.Equal(t, "CREATE INDEX users_id ON users (id);", plan[0].Script)
+	assert.True(t, plan[1].NoTransaction)
+
+	plan = make(migration.Plan, 0)
+	_, err = mig.Down([]string{"table"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	require.Len(t, plan, 1)
+	assert.Equal(t, "DROP TABLE users;", plan[0].Script)
+}
+
+func TestNoTransaction(t *testing.T) {
+	source := &MockSource{}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	source.statements = nil
+	summary, err := mig.Up([]string{"index"})
+	require.NoError(t, err)
+	assert.Len(t, summary, 2)
+
+	require.Len(t, source.statements, 6)
+	assert.Equal(t, "BEGIN", source.statements[0])
+	assert.Equal(t, "COMMIT", source.statements[3])
+	assert.Equal(t, "CREATE INDEX CONCURRENTLY users_email ON users (email)", source.statements[4])
+}
+
+func TestTransactionMode(t *testing.T) {
+	source := &MockSource{fail: "users_id"}
+	mig, err := migration.NewMigration(
+		source, newMockFS(),
+		migration.WithRoot("migrations"),
+		migration.WithTransactionMode(migration.TransactionPerStep),
+	)
+	require.NoError(t, err)
+
+	summary, err := mig.Up([]string{"table", "index"})
+	require.Error(t, err)
+
+	var migErr *migration.MigrationError
+	require.ErrorAs(t, err, &migErr)
+	assert.Equal(t, "create users", migErr.Name)
+	assert.Equal(t, "index", migErr.Stage)
+	require.Len(t, summary, 1)
+	assert.Equal(t, "create users", summary[0].Name)
+	assert.Equal(t, "table", summary[0].Stage)
+}
+
+func TestStepsAndBatches(t *testing.T) {
+	source := &MockSource{}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	plan := make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table", "index"}, migration.Target(1741791024), migration.DryRun(&plan))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"create users"}, plan.Names())
+
+	source.applied = []migration.Migrated{
+		{Name: "create users", Stage: "table", Batch: 1},
+		{Name: "create users", Stage: "index", Batch: 2},
+		{Name: "users email", Stage: "index", Batch: 2},
+	}
+
+	plan = make(migration.Plan, 0)
+	summary, err := mig.Up([]string{"table", "index"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	assert.True(t, summary.IsEmpty())
+
+	plan = make(migration.Plan, 0)
+	_, err = mig.Down([]string{"table", "index"}, migration.Batches(1), migration.DryRun(&plan))
+	require.NoError(t, err)
+	require.Len(t, plan, 1)
+	assert.Equal(t, "create users", plan[0].Name)
+	assert.Equal(t, "index", plan[0].Stage)
+
+	plan = make(migration.Plan, 0)
+	_, err = mig.Down([]string{"table", "index"}, migration.Steps(1), migration.DryRun(&plan))
+	require.NoError(t, err)
+	assert.Len(t, plan, 2)
+	assert.Equal(t, []string{"create users"}, plan.Names())
+}
+
+func TestRegister(t *testing.T) {
+	source := &MockSource{}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	called := false
+	err = mig.Register(
+		1741791024, "backfill users", "table",
+		func(ctx context.Context, db migration.ExecutableScanner) error {
+			called = true
+			return db.Exec(ctx, "UPDATE users SET id = id;")
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	err = mig.Register(1741791024, "create users", "table", func(context.Context, migration.ExecutableScanner) error { return nil }, nil)
+	assert.Error(t, err)
+
+	source.statements = nil
+	summary, err := mig.Up([]string{"table"})
+	require.NoError(t, err)
+	assert.True(t, called)
+	assert.ElementsMatch(t, []string{"create users", "backfill users"}, summary.Names())
+	assert.Contains(t, source.statements, "UPDATE users SET id = id;")
+}
+
+func TestNestedNames(t *testing.T) {
+	fs := &MockFS{
+		files: map[string]string{
+			"migrations/clients/1742106401-order-tables.sql": "-- { up: table }\nCREATE TABLE client_orders (id INT);",
+			"migrations/shop/1742106402-order-tables.sql":    "-- { up: table }\nCREATE TABLE shop_orders (id INT);",
+			"migrations/shop/1742106403-products.sql":        "-- { up: table }\nCREATE TABLE products (id INT);",
+		},
+	}
+
+	source := &MockSource{
+		applied: []migration.Migrated{{Name: "products", Stage: "table"}},
+	}
+	mig, err := migration.NewMigration(source, fs, migration.WithRoot("migrations"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`UPDATE "migrations" SET name = $1 WHERE name = $2;`}, source.statements)
+
+	source.applied = []migration.Migrated{{Name: "shop/products", Stage: "table"}}
+	plan := make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"clients/order tables", "shop/order tables"}, plan.Names())
+
+	plan = make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table"}, migration.OnlyFiles("shop/*"), migration.DryRun(&plan))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shop/order tables"}, plan.Names())
+
+	legacy, err := migration.NewMigration(source, fs, migration.WithRoot("migrations"), migration.WithLegacyNames(true))
+	require.NoError(t, err)
+	plan = make(migration.Plan, 0)
+	_, err = legacy.Up([]string{"table"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"order tables", "products"}, plan.Names())
+}
+
+func TestTemplateData(t *testing.T) {
+	fs := &MockFS{
+		files: map[string]string{
+			"migrations/1742106401-create-orders.sql": "-- { up: table }\nCREATE TABLE {{ .Schema }}.orders (id INT);",
+		},
+	}
+
+	_, err := migration.NewMigration(&MockSource{}, fs, migration.WithRoot("migrations"), migration.WithTemplateData(map[string]any{}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Schema")
+
+	mig, err := migration.NewMigration(
+		&MockSource{}, fs,
+		migration.WithRoot("migrations"),
+		migration.WithTemplateData(map[string]any{"Schema": "tenant"}),
+	)
+	require.NoError(t, err)
+
+	plan := make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	require.Len(t, plan, 1)
+	assert.Equal(t, "CREATE TABLE tenant.orders (id INT);", plan[0].Script)
+}
+
+func TestMetadata(t *testing.T) {
+	source := &MockSource{}
+	mig, err := migration.NewMigration(
+		source, newMockFS(),
+		migration.WithRoot("migrations"),
+		migration.WithVersion("v1.2.0"),
+	)
+	require.NoError(t, err)
+
+	summary, err := mig.Up([]string{"table"})
+	require.NoError(t, err)
+	require.Len(t, summary, 1)
+	assert.Equal(t, "v1.2.0", summary[0].Version)
+	assert.Equal(t, 1, summary[0].Batch)
+
+	require.Len(t, source.inserts, 1)
+	assert.Equal(t, "create users", source.inserts[0][0])
+	assert.Equal(t, "table", source.inserts[0][1])
+	assert.Equal(t, "v1.2.0", source.inserts[0][7])
+}
+
+func TestStatementError(t *testing.T) {
+	source := &MockSource{fail: "users_id"}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	_, err = mig.Up([]string{"index"})
+	var stmtErr *migration.StatementError
+	require.ErrorAs(t, err, &stmtErr)
+	assert.Equal(t, 9, stmtErr.Line)
+	assert.Equal(t, "CREATE INDEX users_id ON users (id)", stmtErr.Statement)
+}
+
+func TestFormats(t *testing.T) {
+	t.Run("golang-migrate", func(t *testing.T) {
+		fs := &MockFS{
+			files: map[string]string{
+				"migrations/0001_create_users.up.sql":   "CREATE TABLE users (id INT);\n",
+				"migrations/0001_create_users.down.sql": "DROP TABLE users;\n",
+				"migrations/0002_add_email.up.sql":      "ALTER TABLE users ADD email TEXT;",
+				"migrations/README.md":                  "# migrations",
+			},
+		}
+
+		mig, err := migration.NewMigration(&MockSource{}, fs,
+			migration.WithRoot("migrations"),
+			migration.WithFormat(migration.GolangMigrateFormat("main")),
+		)
+		require.NoError(t, err)
+
+		plan := make(migration.Plan, 0)
+		_, err = mig.Up([]string{"main"}, migration.DryRun(&plan))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"create users", "add email"}, plan.Names())
+		assert.Equal(t, "CREATE TABLE users (id INT);", plan[0].Script)
+
+		source := &MockSource{applied: []migration.Migrated{{Name: "create users", Stage: "main"}}}
+		mig, err = migration.NewMigration(source, fs,
+			migration.WithRoot("migrations"),
+			migration.WithFormat(migration.GolangMigrateFormat("main")),
+		)
+		require.NoError(t, err)
+
+		plan = make(migration.Plan, 0)
+		_, err = mig.Down([]string{"main"}, migration.DryRun(&plan))
+		require.NoError(t, err)
+		require.Len(t, plan, 1)
+		assert.Equal(t, "DROP TABLE users;", plan[0].Script)
+	})
+
+	t.Run("goose", func(t *testing.T) {
+		fs := &MockFS{
+			files: map[string]string{
+				"migrations/00001_users.sql": `-- +goose Up
+-- +goose NO TRANSACTION
+-- +goose StatementBegin
+CREATE INDEX CONCURRENTLY users_idx ON users (id);
+-- +goose StatementEnd
+
+-- +goose Down
+DROP INDEX users_idx;`,
+			},
+		}
+
+		mig, err := migration.NewMigration(&MockSource{}, fs,
+			migration.WithRoot("migrations"),
+			migration.WithFormat(migration.GooseFormat("index")),
+		)
+		require.NoError(t, err)
+
+		plan := make(migration.Plan, 0)
+		_, err = mig.Up([]string{"index"}, migration.DryRun(&plan))
+		require.NoError(t, err)
+		require.Len(t, plan, 1)
+		assert.Equal(t, "users", plan[0].Name)
+		assert.Equal(t, "CREATE INDEX CONCURRENTLY users_idx ON users (id);", plan[0].Script)
+		assert.True(t, plan[0].NoTransaction)
+	})
+
+	t.Run("flyway", func(t *testing.T) {
+		fs := &MockFS{
+			files: map[string]string{
+				"migrations/V1__create_users.sql": "CREATE TABLE users (id INT);",
+				"migrations/U1__create_users.sql": "DROP TABLE users;",
+				"migrations/V2__add_email.sql":    "ALTER TABLE users ADD email TEXT;",
+			},
+		}
+
+		source := &MockSource{results: map[string][][]any{"flyway_schema_history": {{"1"}}}}
+		mig, err := migration.NewMigration(source, fs,
+			migration.WithRoot("migrations"),
+			migration.WithFormat(migration.FlywayFormat("main")),
+		)
+		require.NoError(t, err)
+
+		plan := make(migration.Plan, 0)
+		_, err = mig.Up([]string{"main"}, migration.DryRun(&plan))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"create users", "add email"}, plan.Names())
+
+		imported, err := mig.Import(migration.FlywayFormat("main"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"create users"}, imported.Names())
+
+		// Dotted versions can't be mapped to timestamps
+		fs.files["migrations/V2.1__patch.sql"] = "UPDATE users SET id = 1;"
+		assert.ErrorContains(t, mig.Load(), `unsupported version "2.1"`)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		fs := &MockFS{
+			files: map[string]string{
+				"migrations/1741791024-create-users.sql":   "-- { up: main }\nCREATE TABLE users (id INT);",
+				"migrations/20240101120000-add-email.sql":  "-- { up: main }\nALTER TABLE users ADD email TEXT;",
+				"migrations/20240101130000-add-phone.sql":  "-- { up: main }\nALTER TABLE users ADD phone TEXT;",
+				"migrations/20240101140000-add-active.sql": "-- { up: main }\nALTER TABLE users ADD active BOOLEAN;",
+			},
+		}
+
+		source := &MockSource{results: map[string][][]any{"schema_migrations": {{int64(20240101130000), false}}}}
+		mig, err := migration.NewMigration(source, fs, migration.WithRoot("migrations"))
+		require.NoError(t, err)
+
+		_, err = mig.Import(migration.GolangMigrateFormat("main"))
+		require.Error(t, err)
+
+		// Only converted files are imported, native files are left pending
+		imported, err := mig.Import(
+			migration.GolangMigrateFormat("main"),
+			"20240101120000-add-email.sql",
+			"20240101130000-add-phone.sql",
+			"20240101140000-add-active.sql",
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"add email", "add phone"}, imported.Names())
+
+		// Files converted outside of the migration root fail the import
+		_, err = mig.Import(migration.GolangMigrateFormat("main"), "20240101150000-add-age.sql")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not in the migration root")
+	})
+}
+
+func TestListener(t *testing.T) {
+	listener := &MockListener{}
+	source := &MockSource{fail: "users_email"}
+	mig, err := migration.NewMigration(source, newMockFS(),
+		migration.WithRoot("migrations"),
+		migration.WithListener(listener),
+	)
+	require.NoError(t, err)
+
+	_, err = mig.Up([]string{"table", "index"}, migration.DryRun(&migration.Plan{}))
+	require.NoError(t, err)
+	assert.Empty(t, listener.events)
+
+	_, err = mig.Up([]string{"table", "index"})
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		"before all 3",
+		"before table create users",
+		"before index create users",
+		"after table create users",
+		"after index create users",
+		"before index users email",
+		"error index users email",
+		"after all 2 true",
+	}, listener.events)
+
+	// Rolled back steps are not reported as done
+	listener.events = nil
+	source = &MockSource{fail: "users_id"}
+	mig, err = migration.NewMigration(source, newMockFS(),
+		migration.WithRoot("migrations"),
+		migration.WithListener(listener),
+	)
+	require.NoError(t, err)
+
+	_, err = mig.Up([]string{"table", "index"})
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		"before all 3",
+		"before table create users",
+		"before index create users",
+		"error index create users",
+		"after all 0 true",
+	}, listener.events)
+}
+
+func TestCLIOutput(t *testing.T) {
+	source := &MockSource{fail: "users_email"}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	cmd := migration.NewMigrationCLI(mig)
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"up", "table", "index", "--output", "json"})
+	require.Error(t, cmd.Execute())
+
+	var doc struct {
+		Command string
+		Result  migration.Summary
+		Error   string
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	assert.Equal(t, "up", doc.Command)
+	assert.Len(t, doc.Result, 2)
+	assert.Contains(t, doc.Error, "users email")
+
+	cmd = migration.NewMigrationCLI(mig)
+	cmd.SetArgs([]string{"summary", "--output", "xml"})
+	assert.Error(t, cmd.Execute())
+
+	// Argument errors are reported in the output format
+	out.Reset()
+	cmd = migration.NewMigrationCLI(mig)
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"import", "--output", "json"})
+	require.Error(t, cmd.Execute())
+	require.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	assert.Equal(t, "import", doc.Command)
+	assert.Contains(t, doc.Error, "accepts 1 arg(s)")
+
+	// Durations are serialized in milliseconds
+	item := migration.Migrated{Name: "create users", Duration: 1500 * time.Millisecond}
+	data, err := json.Marshal(item)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"duration_ms":1500`)
+
+	var decoded migration.Migrated
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, item, decoded)
+
+	data, err = yaml.Marshal(item)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "duration_ms: 1500")
+
+	decoded = migration.Migrated{}
+	require.NoError(t, yaml.Unmarshal(data, &decoded))
+	assert.Equal(t, item, decoded)
+}
+
+func TestLint(t *testing.T) {
+	fs := &MockFS{
+		files: map[string]string{
+			"migrations/1741791024-users.sql":   "-- { up: table }\nCREATE TABLE users (id INT);\n-- { down: table }\nDROP TABLE users;",
+			"migrations/1741791024-orders.sql":  "-- { up: table }\nCREATE TABLE orders (id INT);\n-- { down: table }\nDROP TABLE orders;",
+			"migrations/1741791020-legacy.sql":  "-- { up: table }\nCREATE TABLE legacy (id INT);\n-- { down: table }\nDROP TABLE legacy;",
+			"migrations/1741791030-empty.sql":   "-- { up: seed }\n-- { down: seed }\n",
+			"migrations/1741791031-no-down.sql": "-- { up: table }\nCREATE TABLE logs (id INT);",
+			"migrations/create_users.sql":       "-- { up: table }\nCREATE TABLE users (id INT);",
+		},
+	}
+
+	source := &MockSource{applied: []migration.Migrated{{Name: "users", Stage: "table"}}}
+	mig, err := migration.NewMigration(source, fs, migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	issues, err := mig.Lint("table")
+	require.NoError(t, err)
+
+	rules := make(map[migration.LintRule][]string)
+	for _, issue := range issues {
+		rules[issue.Rule] = append(rules[issue.Rule], issue.Name)
+	}
+	assert.Equal(t, []string{"migrations/create_users.sql"}, rules[migration.LintIgnoredFile])
+	assert.Len(t, rules[migration.LintDuplicateTimestamp], 1)
+	assert.Equal(t, []string{"empty"}, rules[migration.LintUnknownStage])
+	assert.Equal(t, []string{"empty"}, rules[migration.LintEmptyUp])
+	assert.Equal(t, []string{"no down"}, rules[migration.LintMissingDown])
+	assert.Equal(t, []string{"legacy"}, rules[migration.LintOutOfOrder])
+
+	// No expected stages, the stage rule is skipped
+	issues, err = mig.Lint()
+	require.NoError(t, err)
+	for _, issue := range issues {
+		assert.NotEqual(t, migration.LintUnknownStage, issue.Rule)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	fs := &MockFS{
+		files: map[string]string{
+			"migrations/1741791024-users.sql": `-- { up: table }
+CREATE TABLE users (id INT, email TEXT);
+CREATE INDEX users_email ON users (email);
+
+-- { up: index }
+CREATE INDEX users_id ON users (id);
+CREATE INDEX CONCURRENTLY users_name ON users (name);
+-- CREATE INDEX users_commented ON users (id);`,
+			"migrations/1741791025-orders.sql": `-- { up: table }
+ALTER TABLE orders ADD COLUMN token UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE orders ADD COLUMN note TEXT DEFAULT 'ALTER COLUMN x TYPE';
+ALTER TABLE orders ALTER COLUMN total TYPE BIGINT;
+ALTER TABLE orders ADD CONSTRAINT orders_user FOREIGN KEY (user_id) REFERENCES users (id);
+ALTER TABLE orders ADD CONSTRAINT orders_client FOREIGN KEY (client_id) REFERENCES clients (id) NOT VALID;`,
+			"migrations/1741791026-allowed.sql": `-- { allow: column-type }
+-- { up: table }
+ALTER TABLE logs ALTER COLUMN id TYPE BIGINT;`,
+		},
+	}
+
+	mig, err := migration.NewMigration(&MockSource{}, fs, migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	findings, err := mig.Analyze()
+	require.NoError(t, err)
+
+	rules := make([]string, 0)
+	for _, finding := range findings {
+		rules = append(rules, finding.Name+":"+finding.Rule)
+	}
+	assert.Equal(t, []string{
+		"users:create-index",
+		"orders:volatile-default",
+		"orders:column-type",
+		"orders:foreign-key",
+	}, rules)
+	assert.Equal(t, 6, findings[0].Line)
+	assert.NotEmpty(t, findings[0].Suggestion)
+
+	t.Run("rules", func(t *testing.T) {
+		fs := &MockFS{
+			files: map[string]string{
+				"migrations/1741791024-rules.sql": `-- { up: table }
+DROP INDEX users_email;
+DROP INDEX CONCURRENTLY users_name;
+ALTER TABLE users ALTER COLUMN email SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_age CHECK (age > 0);
+ALTER TABLE users ADD CONSTRAINT users_score CHECK (score > 0) NOT VALID;`,
+				"migrations/1741791025-qualified.sql": `-- { up: table }
+CREATE TABLE public.accounts (id INT, owner INT);
+CREATE INDEX accounts_owner ON accounts (owner);
+ALTER TABLE "public"."accounts" ADD CONSTRAINT accounts_id CHECK (id > 0);
+CREATE INDEX logs_id ON audit.logs (id);`,
+			},
+		}
+
+		mig, err := migration.NewMigration(&MockSource{}, fs, migration.WithRoot("migrations"))
+		require.NoError(t, err)
+
+		findings, err := mig.AnalyzeContext(context.Background())
+		require.NoError(t, err)
+
+		rules := make([]string, 0)
+		for _, finding := range findings {
+			rules = append(rules, finding.Name+":"+finding.Rule)
+		}
+		assert.Equal(t, []string{
+			"rules:drop-index",
+			"rules:set-not-null",
+			"rules:check-constraint",
+			"qualified:create-index",
+		}, rules)
+	})
+
+	t.Run("template", func(t *testing.T) {
+		fs := &MockFS{
+			files: map[string]string{
+				"migrations/1741791024-users.sql": `-- { up: table }
+CREATE INDEX {{ .Concurrently }} users_email ON {{ .Schema }}.users (email);`,
+			},
+		}
+
+		mig, err := migration.NewMigration(&MockSource{}, fs,
+			migration.WithRoot("migrations"),
+			migration.WithTemplateData(map[string]any{"Schema": "tenant", "Concurrently": ""}),
+		)
+		require.NoError(t, err)
+
+		findings, err := mig.Analyze()
+		require.NoError(t, err)
+		require.Len(t, findings, 1)
+		assert.Equal(t, "CREATE INDEX  users_email ON tenant.users (email)", findings[0].Statement)
+
+		mig, err = migration.NewMigration(&MockSource{}, fs,
+			migration.WithRoot("migrations"),
+			migration.WithTemplateData(map[string]any{"Schema": "tenant", "Concurrently": "CONCURRENTLY"}),
+		)
+		require.NoError(t, err)
+
+		findings, err = mig.Analyze()
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+	})
+}
+
+func TestCheckReversible(t *testing.T) {
+	source := &MockSource{}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	source.statements = nil
+	result, err := mig.CheckReversible([]string{"table", "index"})
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+	assert.True(t, result[0].Reversible())
+	assert.True(t, result[1].Reversible())
+	assert.False(t, result[2].Reversible())
+	assert.Equal(t, []string{
+		"BEGIN",
+		"CREATE TABLE users (id INT)",
+		"DROP TABLE users",
+		"CREATE TABLE users (id INT)",
+		"CREATE INDEX users_id ON users (id)",
+		"DROP INDEX users_id",
+		"CREATE INDEX users_id ON users (id)",
+		"ROLLBACK",
+	}, source.statements)
+
+	t.Run("schema", func(t *testing.T) {
+		fs := &MockFS{
+			files: map[string]string{
+				"migrations/1741791024-leftover.sql": `-- { up: table }
+CREATE TABLE users (id INT);
+CREATE TABLE logs (id INT);
+-- { down: table }
+DROP TABLE users;`,
+				"migrations/1741791025-missing.sql": `-- { up: table }
+CREATE TABLE tmp (id INT);
+-- { down: table }
+DROP TABLE tmp;
+DROP TABLE legacy;`,
+			},
+		}
+
+		source := &SchemaSource{
+			MockSource: &MockSource{},
+			dialect:    migration.DialectPostgres,
+			tables:     map[string]bool{"legacy": true},
+		}
+		mig, err := migration.NewMigration(source, fs, migration.WithRoot("migrations"))
+		require.NoError(t, err)
+
+		result, err := mig.CheckReversible([]string{"table"})
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.False(t, result[0].Reversible())
+		assert.Equal(t, []string{"table logs"}, result[0].Leftover)
+		assert.Empty(t, result[0].Missing)
+		assert.False(t, result[1].Reversible())
+		assert.Equal(t, []string{"table legacy"}, result[1].Missing)
+		assert.Empty(t, result[1].Leftover)
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		source := &SchemaSource{MockSource: &MockSource{}, dialect: migration.DialectMySQL, tables: map[string]bool{}}
+		mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+		require.NoError(t, err)
+
+		_, err = mig.CheckReversible([]string{"table"})
+		assert.ErrorIs(t, err, migration.ErrUnsupportedDialect)
+
+		scratch := &SchemaSource{MockSource: &MockSource{}, dialect: migration.DialectMySQL, tables: map[string]bool{}}
+		source.statements = nil
+		result, err := mig.CheckReversible([]string{"table"}, migration.Scratch(scratch))
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.True(t, result[0].Reversible())
+		assert.Empty(t, source.statements)
+		assert.Contains(t, scratch.statements, "DROP TABLE users")
+	})
+
+	t.Run("baseline", func(t *testing.T) {
+		fs := &MockFS{
+			files: map[string]string{
+				"migrations/1741791025-baseline-1741791025-table.sql": "-- { options: baseline }\n-- { squashes: create users }\n-- { up: table }\nCREATE TABLE users (id INT);\n-- { down: table }\nDROP TABLE users;",
+			},
+		}
+
+		source := &MockSource{applied: []migration.Migrated{{Name: "create users", Stage: "table"}}}
+		mig, err := migration.NewMigration(source, fs, migration.WithRoot("migrations"))
+		require.NoError(t, err)
+
+		source.statements = nil
+		result, err := mig.CheckReversible([]string{"table"})
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.False(t, result[0].Reversible())
+		assert.Contains(t, result[0].Error, "baseline")
+		assert.Equal(t, []string{"BEGIN", "ROLLBACK"}, source.statements)
+	})
+}
+
+func TestRepeatable(t *testing.T) {
+	fs := &MockFS{
+		files: map[string]string{
+			"migrations/1741791024-users.sql":  "-- { up: table }\nCREATE TABLE users (id INT);\n-- { repeatable: view }\nCREATE OR REPLACE VIEW active_users AS SELECT * FROM users;",
+			"migrations/R-reports.sql":         "-- { up: view }\nCREATE OR REPLACE VIEW reports AS SELECT 1;",
+			"migrations/1741791025-orders.sql": "-- { up: view }\nCREATE TABLE orders (id INT);",
+		},
+	}
+
+	source := &MockSource{}
+	mig, err := migration.NewMigration(source, fs, migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	plan := make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table", "view"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	require.Len(t, plan, 4)
+	assert.Equal(t, []string{"users", "orders", "reports"}, plan.Names())
+	assert.Equal(t, "users", plan[3].Name)
+	assert.True(t, plan[2].Repeatable)
+	assert.True(t, plan[3].Repeatable)
+
+	// Unchanged repeatable files are skipped, changed ones re-run
+	reports := plan[2].Script
+	source.applied = []migration.Migrated{
+		{Name: "users", Stage: "table", Checksum: checksumOf(plan[0].Script)},
+		{Name: "orders", Stage: "view", Checksum: checksumOf(plan[1].Script)},
+		{Name: "reports", Stage: "view", Checksum: checksumOf(reports), Repeatable: true},
+		{Name: "users", Stage: "view", Checksum: "outdated", Repeatable: true},
+	}
+	plan = make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table", "view"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users"}, plan.Names())
+	assert.Equal(t, "view", plan[0].Stage)
+
+	source.statements = nil
+	_, err = mig.Up([]string{"view"})
+	require.NoError(t, err)
+	assert.Contains(t, source.statements, `DELETE FROM "migrations" WHERE name IN ($1, $2) AND stage = $3 AND kind = $4;`)
+	require.NotEmpty(t, source.inserts)
+	assert.Equal(t, "repeatable:users", source.inserts[len(source.inserts)-1][0])
+
+	drifts, err := mig.Verify()
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	fs.files["migrations/1741791026-invalid.sql"] = "-- { up: view }\nSELECT 1;\n-- { repeatable: view }\nSELECT 2;"
+	assert.Error(t, mig.Load())
+}
+
+func TestRepeatableSteps(t *testing.T) {
+	fs := &MockFS{
+		files: map[string]string{
+			"migrations/1741791024-users.sql":  "-- { up: table }\nCREATE TABLE users (id INT);",
+			"migrations/1741791025-orders.sql": "-- { up: table }\nCREATE TABLE orders (id INT);",
+			"migrations/R-view.sql":            "-- { up: view }\nCREATE OR REPLACE VIEW orders_view AS SELECT * FROM orders;",
+			"migrations/R-users.sql":           "-- { up: view }\nCREATE OR REPLACE VIEW users_view AS SELECT * FROM users;",
+		},
+	}
+
+	mig, err := migration.NewMigration(&MockSource{}, fs, migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	// Steps count versioned files, repeatable files wait for the left out files
+	plan := make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table", "view"}, migration.DryRun(&plan), migration.Steps(1))
+	require.NoError(t, err)
+	require.Len(t, plan, 1)
+	assert.Equal(t, "users", plan[0].Name)
+	assert.False(t, plan[0].Repeatable)
+
+	plan = make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table", "view"}, migration.DryRun(&plan), migration.Steps(2))
+	require.NoError(t, err)
+	require.Len(t, plan, 4)
+	assert.Equal(t, []string{"users", "orders", "users", "view"}, []string{plan[0].Name, plan[1].Name, plan[2].Name, plan[3].Name})
+	assert.True(t, plan[2].Repeatable)
+	assert.True(t, plan[3].Repeatable)
+}
+
+func TestRepeatableRollback(t *testing.T) {
+	fs := &MockFS{
+		files: map[string]string{
+			"migrations/1741791024-users.sql":  "-- { up: table }\nCREATE TABLE users (id INT);\n-- { down: table }\nDROP TABLE users;",
+			"migrations/1741791025-orders.sql": "-- { up: table }\nCREATE TABLE orders (id INT);\n-- { down: table }\nDROP TABLE orders;",
+			"migrations/R-users.sql":           "-- { up: table }\nCREATE OR REPLACE VIEW active_users AS SELECT * FROM users;",
+		},
+	}
+
+	source := &MockSource{}
+	mig, err := migration.NewMigration(source, fs, migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	// Versioned and repeatable files of the same name and stage are tracked separately
+	plan := make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	require.Len(t, plan, 3)
+	view := plan[2].Script
+
+	source.applied = []migration.Migrated{
+		{Name: "users", Stage: "table", Checksum: checksumOf(plan[0].Script), Batch: 1},
+		{Name: "orders", Stage: "table", Checksum: checksumOf(plan[1].Script), Batch: 1},
+		{Name: "repeatable:users", Stage: "table", Checksum: checksumOf(view), Batch: 1, Repeatable: true},
+	}
+	plan = make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	assert.Empty(t, plan)
+
+	status, err := mig.Status()
+	require.NoError(t, err)
+	assert.Empty(t, status.Orphaned())
+
+	// Down clears the repeatable entries of the stage to rerun them on next up
+	plan = make(migration.Plan, 0)
+	_, err = mig.Down([]string{"table"}, migration.DryRun(&plan), migration.Steps(1))
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	assert.Equal(t, "users", plan[0].Name)
+	assert.True(t, plan[0].Repeatable)
+	assert.Equal(t, migration.DirectionDown, plan[0].Direction)
+	assert.Equal(t, "orders", plan[1].Name)
+	assert.False(t, plan[1].Repeatable)
+
+	source.statements = nil
+	_, err = mig.Down([]string{"table"}, migration.Steps(1))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"BEGIN",
+		`DELETE FROM "migrations" WHERE name IN ($1, $2) AND stage = $3 AND kind = $4;`,
+		"DROP TABLE orders",
+		`DELETE FROM "migrations" WHERE name = $1 AND stage = $2;`,
+		"COMMIT",
+	}, source.statements)
+
+	// Refresh reruns the repeatable files after the stage
+	plan = make(migration.Plan, 0)
+	_, err = mig.Refresh([]string{"table"}, migration.DryRun(&plan), migration.Steps(1))
+	require.NoError(t, err)
+	require.Len(t, plan, 3)
+	assert.Equal(t, migration.DirectionDown, plan[0].Direction)
+	assert.Equal(t, "orders", plan[0].Name)
+	assert.Equal(t, migration.DirectionUp, plan[1].Direction)
+	assert.Equal(t, "orders", plan[1].Name)
+	assert.True(t, plan[2].Repeatable)
+	assert.Equal(t, "users", plan[2].Name)
+	assert.Equal(t, view, plan[2].Script)
+}
+
+func checksumOf(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestTenantRunner(t *testing.T) {
+	var mu sync.Mutex
+	sources := make(map[string]*MockSource)
+	resolver := func(ctx context.Context, tenant string) (migration.MigrationSource, error) {
+		if tenant == "broken" {
+			return nil, errors.New("connection refused")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		sources[tenant] = &MockSource{}
+		return sources[tenant], nil
+	}
+
+	runner := migration.NewTenantRunner(
+		resolver,
+		migration.StaticTenants("alpha", "beta", "broken"),
+		newMockFS(),
+		migration.WithParallelism(2),
+		migration.WithTenantOptions(migration.WithRoot("migrations")),
+	)
+
+	report, err := runner.Up(context.Background(), nil, []string{"table"})
+	require.NoError(t, err)
+	require.Len(t, report, 3)
+	assert.Equal(t, "alpha", report[0].Tenant)
+	assert.Len(t, report[0].Result, 1)
+	assert.Len(t, report[1].Result, 1)
+	assert.Len(t, report.Failed(), 1)
+	assert.ErrorContains(t, report.Err(), `tenant "broken"`)
+
+	report, err = runner.Up(context.Background(), []string{"beta", "beta"}, []string{"table"})
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.NoError(t, report.Err())
+
+	// Tenants share the plan on dry-run
+	_, err = runner.Up(context.Background(), nil, []string{"table"}, migration.DryRun(&migration.Plan{}))
+	assert.Error(t, err)
+
+	// Cancellation stops tenant initialization
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := migration.NewTenantRunner(
+		func(_ context.Context, tenant string) (migration.MigrationSource, error) {
+			cancel()
+			mu.Lock()
+			defer mu.Unlock()
+			sources[tenant] = &MockSource{}
+			return sources[tenant], nil
+		},
+		migration.StaticTenants("gamma"),
+		newMockFS(),
+		migration.WithTenantOptions(migration.WithRoot("migrations")),
+	)
+	report, err = cancelled.Up(ctx, nil, []string{"table"})
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.ErrorIs(t, report[0].Err, context.Canceled)
+	assert.Zero(t, sources["gamma"].created)
+}
+
+func TestMark(t *testing.T) {
+	source := &MockSource{}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	result, err := mig.Mark([]string{"table", "index"}, migration.OnlyFiles("create users"))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Len(t, source.inserts, 2)
+	for _, statement := range source.statements {
+		assert.NotContains(t, statement, "CREATE")
+	}
+
+	source.applied = []migration.Migrated{
+		{Name: "create users", Stage: "table", Batch: 1},
+		{Name: "create users", Stage: "index", Batch: 1},
+		{Name: "hotfix", Stage: "index", Batch: 2},
+	}
+
+	plan := make(migration.Plan, 0)
+	_, err = mig.Unmark([]string{"index"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hotfix", "create users"}, plan.Names())
+
+	source.statements = nil
+	result, err = mig.Unmark([]string{"index"}, migration.OnlyFiles("hotfix"))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "hotfix", result[0].Name)
+	assert.Contains(t, source.statements, `DELETE FROM "migrations" WHERE name = $1 AND stage = $2;`)
+
+	// Structured output requires explicit confirmation
+	cmd := migration.NewMigrationCLI(mig)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"unmark", "index", "--output", "json"})
+	assert.Error(t, cmd.Execute())
+
+	source.statements = nil
+	cmd = migration.NewMigrationCLI(mig)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetIn(strings.NewReader("n\n"))
+	cmd.SetArgs([]string{"unmark", "index"})
+	require.NoError(t, cmd.Execute())
+	assert.NotContains(t, source.statements, `DELETE FROM "migrations" WHERE name = $1 AND stage = $2;`)
+}
+
+func TestSquash(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"1741791024-create-users.sql":  "-- { up: table }\nCREATE TABLE users (id INT);\n-- { down: table }\nDROP TABLE users;\n-- { up: index }\nCREATE INDEX users_id ON users (id);",
+		"1741791025-create-orders.sql": "-- { up: table }\nCREATE TABLE orders (id INT);\n-- { down: table }\nDROP TABLE orders;",
+		"1741791026-add-email.sql":     "-- { up: table }\nALTER TABLE users ADD email TEXT;",
+	}
+	mock := &MockFS{files: make(map[string]string)}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0644))
+		mock.files["migrations/"+name] = content
+	}
+
+	mig, err := migration.NewMigration(&MockSource{}, mock, migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	plan := make(migration.Plan, 0)
+	result, err := mig.Squash(1741791025, root, migration.DryRun(&plan))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1741791024-baseline-1741791025-table.sql", "1741791025-baseline-1741791025-index.sql"}, result.Files)
+	assert.Equal(t, []string{"1741791024-create-users.sql", "1741791025-create-orders.sql"}, result.Squashed)
+	assert.Equal(t, []string{"baseline 1741791025 table", "baseline 1741791025 index"}, plan.Names())
+	assert.FileExists(t, filepath.Join(root, "1741791024-create-users.sql"))
+
+	_, err = mig.Squash(1741791025, root)
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(root, "1741791024-create-users.sql"))
+
+	// Reload squashed files
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	mock.files = make(map[string]string)
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(root, entry.Name()))
+		require.NoError(t, err)
+		mock.files["migrations/"+entry.Name()] = string(content)
+	}
+	assert.Contains(t, mock.files["migrations/1741791024-baseline-1741791025-table.sql"], "-- { squashes: create users, create orders }")
+	assert.Contains(t, mock.files["migrations/1741791024-baseline-1741791025-table.sql"], "DROP TABLE orders;\n\n-- create users\nDROP TABLE users;")
+	assert.Contains(t, mock.files["migrations/1741791025-baseline-1741791025-index.sql"], "-- { squashes: create users }")
+
+	// New databases run the baseline
+	source := &MockSource{}
+	mig, err = migration.NewMigration(source, mock, migration.WithRoot("migrations"))
+	require.NoError(t, err)
+	issues, err := mig.Lint("table", "index")
+	require.NoError(t, err)
+	for _, issue := range issues {
+		assert.NotEqual(t, migration.LintDuplicateTimestamp, issue.Rule)
+	}
+
+	plan = make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table", "index"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	require.Len(t, plan, 3)
+	assert.False(t, plan[0].Baseline)
+	assert.Contains(t, plan[0].Script, "CREATE TABLE orders (id INT);")
+
+	// Existing databases record the baseline without running it
+	source.applied = []migration.Migrated{
+		{Name: "create users", Stage: "table"},
+		{Name: "create orders", Stage: "table"},
+		{Name: "create users", Stage: "index"},
+	}
+	_, err = mig.Up([]string{"table", "index"})
+	require.NoError(t, err)
+	for _, statement := range source.statements {
+		assert.NotContains(t, statement, "CREATE")
+	}
+	assert.Contains(t, source.statements, "ALTER TABLE users ADD email TEXT")
+	assert.Contains(t, source.statements, `DELETE FROM "migrations" WHERE name = $1 AND stage = $2;`)
+	assert.Len(t, source.inserts, 3)
+
+	// Partially applied squashed files can't be replaced
+	source = &MockSource{
+		applied: []migration.Migrated{
+			{Name: "create users", Stage: "table"},
+			{Name: "unrelated", Stage: "table"},
+		},
+	}
+	mig, err = migration.NewMigration(source, mock, migration.WithRoot("migrations"))
+	require.NoError(t, err)
+	_, err = mig.Up([]string{"table"}, migration.DryRun(&migration.Plan{}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing: create orders")
+
+	_, err = mig.Up([]string{"table"})
+	require.Error(t, err)
+	assert.Empty(t, source.statements)
+
+	// Squashing again lists earlier baselines by their unique names
+	_, err = mig.Squash(1741791025, root)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already squashed to 1741791025")
+
+	result, err = mig.Squash(1741791026, root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1741791025-baseline-1741791026-table.sql", "1741791026-baseline-1741791026-index.sql"}, result.Files)
+	assert.NoFileExists(t, filepath.Join(root, "1741791024-baseline-1741791025-table.sql"))
+	content, err := os.ReadFile(filepath.Join(root, "1741791025-baseline-1741791026-table.sql"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "-- { squashes: baseline 1741791025 table, add email }")
+
+	// Names not fitting the squashes directive are rejected
+	mock = &MockFS{files: map[string]string{
+		"migrations/v1.2/1741791024-create-users.sql": files["1741791024-create-users.sql"],
+	}}
+	mig, err = migration.NewMigration(&MockSource{}, mock, migration.WithRoot("migrations"))
+	require.NoError(t, err)
+	_, err = mig.Squash(1741791025, t.TempDir())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can not be listed in the baseline squashes directive")
+}
+
+func TestLock(t *testing.T) {
+	source := &MockSource{}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"), migration.WithTable("core_migrations"))
+	require.NoError(t, err)
+
+	_, err = mig.Up([]string{"table"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"migration:core_migrations", "migration:core_migrations"}, source.locks)
+
+	source = &MockSource{held: true}
+	_, err = migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.ErrorIs(t, err, migration.ErrLockTimeout)
+
+	source = &MockSource{}
+	mig, err = migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"), migration.WithLockTimeout(time.Second))
+	require.NoError(t, err)
+
+	source.held = true
+	_, err = mig.Up([]string{"table"})
+	require.ErrorIs(t, err, migration.ErrLockTimeout)
+	assert.NotContains(t, source.statements, "CREATE TABLE users (id INT)")
+
+	// Sources without lock support are not locked
+	plain := PlainSource{mock: &MockSource{held: true}}
+	mig, err = migration.NewMigration(plain, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	_, err = mig.Up([]string{"table"})
+	require.NoError(t, err)
+	// Scripts of sources without dialect run unsplit
+	assert.Contains(t, plain.mock.statements, "CREATE TABLE users (id INT);")
+}
+
+func TestVerify(t *testing.T) {
+	source := &MockSource{}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	plan := make(migration.Plan, 0)
+	_, err = mig.Up([]string{"table", "index"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+
+	source.applied = []migration.Migrated{
+		{Name: "create users", Stage: "table", Checksum: checksumOf(plan[0].Script)},
+		{Name: "create users", Stage: "index", Checksum: "outdated"},
+		{Name: "users email", Stage: "index"},
+		{Name: "dropped", Stage: "table", Checksum: "removed"},
+	}
+	drifts, err := mig.Verify()
+	require.NoError(t, err)
+	require.Len(t, drifts, 3)
+	assert.Equal(t, migration.DriftChanged, drifts[0].State)
+	assert.Equal(t, "users email", drifts[1].Name)
+	assert.Equal(t, migration.DriftUnknown, drifts[1].State)
+	assert.Equal(t, "dropped", drifts[2].Name)
+	assert.Equal(t, migration.DriftRemoved, drifts[2].State)
+}
+
+func TestInitializeUpgrade(t *testing.T) {
+	source := &MockSource{missing: []string{"checksum", "kind"}}
+	_, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+	assert.Contains(t, source.statements, `ALTER TABLE "migrations" ADD COLUMN checksum VARCHAR(64) NULL;`)
+	assert.Contains(t, source.statements, `ALTER TABLE "migrations" ADD COLUMN kind VARCHAR(20) NULL;`)
+	assert.NotContains(t, source.statements, `ALTER TABLE "migrations" ADD COLUMN batch INT NULL;`)
+}
+
+func TestTable(t *testing.T) {
+	source := &MockSource{}
+	core, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"), migration.WithTable("app.core_migrations"))
+	require.NoError(t, err)
+	plugin, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"), migration.WithTable("plugin_migrations"))
+	require.NoError(t, err)
+
+	_, err = core.Up([]string{"table"})
+	require.NoError(t, err)
+	_, err = plugin.Up([]string{"table"})
+	require.NoError(t, err)
+
+	inserts := make([]string, 0)
+	for _, statement := range source.statements {
+		if strings.HasPrefix(statement, "INSERT INTO") {
+			inserts = append(inserts, strings.Fields(statement)[2])
+		}
+	}
+	assert.Equal(t, []string{`"app.core_migrations"`, `"plugin_migrations"`}, inserts)
+	assert.Equal(t, []string{
+		"migration:app.core_migrations",
+		"migration:plugin_migrations",
+		"migration:app.core_migrations",
+		"migration:plugin_migrations",
+	}, source.locks)
+
+	// Identifiers of sources without quoting support are used as is
+	plain := PlainSource{mock: &MockSource{}}
+	mig, err := migration.NewMigration(plain, newMockFS(), migration.WithRoot("migrations"), migration.WithTable("plugin_migrations"))
+	require.NoError(t, err)
+	plain.mock.applied = []migration.Migrated{{Name: "create users", Stage: "table"}}
+	_, err = mig.Down([]string{"table"})
+	require.NoError(t, err)
+	assert.Contains(t, plain.mock.statements, `DELETE FROM plugin_migrations WHERE name = ? AND stage = ?;`)
+}
+
+func TestRefresh(t *testing.T) {
+	source := &MockSource{
+		applied: []migration.Migrated{
+			{Name: "create users", Stage: "table"},
+			{Name: "create users", Stage: "index"},
+			{Name: "users email", Stage: "index"},
+		},
+	}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	// Each stage is rolled back in reverse order, then re-applied.
+	// Files without down section are neither rolled back nor re-applied.
+	plan := make(migration.Plan, 0)
+	summary, err := mig.Refresh([]string{"table", "index"}, migration.DryRun(&plan))
+	require.NoError(t, err)
+	require.Len(t, plan, 4)
+	steps := make([]string, 0)
+	for _, step := range plan {
+		steps = append(steps, string(step.Direction)+" "+step.Stage+" "+step.Name)
+	}
+	assert.Equal(t, []string{
+		"down table create users",
+		"up table create users",
+		"down index create users",
+		"up index create users",
+	}, steps)
+	assert.Len(t, summary, 2)
+
+	// Failures name the direction, file and stage
+	source.fail = "CREATE INDEX users_id"
+	_, err = mig.Refresh([]string{"table", "index"})
+	require.Error(t, err)
+	assert.Equal(t, `up "create users" on index stage: line 9: mock failure`, err.Error())
+	assert.Contains(t, source.statements, "ROLLBACK")
+}
+
+func TestStatus(t *testing.T) {
+	source := &MockSource{
+		applied: []migration.Migrated{
+			{Name: "create users", Stage: "table"},
+			{Name: "dropped", Stage: "table"},
+		},
+	}
+	mig, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+
+	report, err := mig.Status()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"table", "index"}, report.Stages())
+
+	states := make([]string, 0)
+	for _, entry := range report {
+		states = append(states, string(entry.State)+" "+entry.Stage+" "+entry.Name)
+	}
+	assert.Equal(t, []string{
+		"applied table create users",
+		"pending index create users",
+		"pending index users email",
+		"orphaned table dropped",
+	}, states)
+	assert.Len(t, report.Applied(), 1)
+	assert.Len(t, report.Pending(), 2)
+	assert.Len(t, report.Orphaned(), 1)
+	assert.Len(t, report.GroupByStage()["index"], 2)
+}
+
+func TestInitializeNameColumn(t *testing.T) {
+	source := &MockSource{results: map[string][][]any{"character_maximum_length": {{100}}}}
+	_, err := migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+	assert.Contains(t, source.statements, `ALTER TABLE "migrations" ALTER COLUMN name TYPE VARCHAR(255);`)
+
+	source = &MockSource{results: map[string][][]any{"character_maximum_length": {{255}}}}
+	_, err = migration.NewMigration(source, newMockFS(), migration.WithRoot("migrations"))
+	require.NoError(t, err)
+	assert.Empty(t, source.statements)
+}
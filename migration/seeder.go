@@ -0,0 +1,335 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-universal/fs"
+)
+
+// Seeder loads fixture data files (CSV, JSON or YAML) and inserts or upserts their rows,
+// tracking applied seeds in a table.
+//
+// Seed files are named "timestamp-table[.env].ext" (e.g. "1741791024-users.dev.json").
+// Files with an environment only run for that environment.
+// Seeds with key columns are upserted and re-applied when the file changes,
+// seeds without key are inserted once.
+//
+// Rows are written through the MigrationSource rather than the postgres and mysql repositories:
+// the repositories insert typed structs only, have no upsert, and run outside the source transaction
+// that records the applied seeds. The statements use the source quoting and placeholders instead.
+type Seeder interface {
+	// Load loads seed files from the filesystem and caches them.
+	Load() error
+
+	// Seed applies pending seeds of the environment with a 300 second timeout.
+	Seed(env string) ([]Seeded, error)
+
+	// SeedContext is like Seed but uses the context.
+	SeedContext(ctx context.Context, env string) ([]Seeded, error)
+}
+
+// Seeded represents an applied seed file.
+type Seeded struct {
+	Name  string `json:"name" yaml:"name"`   // Seed file name.
+	Table string `json:"table" yaml:"table"` // Seeded table.
+	Env   string `json:"env" yaml:"env"`     // Seed environment, empty for all environments.
+	Rows  int    `json:"rows" yaml:"rows"`   // Number of inserted or upserted rows.
+}
+
+type seeder struct {
+	root        string
+	table       string
+	dev         bool
+	lockTimeout time.Duration
+	files       []seedFile
+	fs          fs.FlexibleFS
+	db          MigrationSource
+	mutex       sync.RWMutex
+}
+
+// SeederOption configures the seeder.
+type SeederOption func(*seeder)
+
+// WithSeedRoot sets the root directory for seed files. Defaults to "seeds".
+func WithSeedRoot(root string) SeederOption {
+	root = normalizePath(root)
+	return func(s *seeder) {
+		if root != "" {
+			s.root = root
+		} else {
+			s.root = "."
+		}
+	}
+}
+
+// WithSeedTable sets the table used to track applied seeds, optionally schema qualified.
+// Defaults to "seeds".
+func WithSeedTable(name string) SeederOption {
+	name = strings.TrimSpace(name)
+	return func(s *seeder) {
+		if name != "" {
+			s.table = name
+		}
+	}
+}
+
+// WithSeedEnv enables development mode, causing Load() to be called on each seed run.
+func WithSeedEnv(isDev bool) SeederOption {
+	return func(s *seeder) {
+		s.dev = isDev
+	}
+}
+
+// WithSeedLockTimeout sets the maximum time to wait for the seed lock held by other processes.
+// Seed fails with ErrLockTimeout when the lock isn't acquired in time. Defaults to 60 seconds.
+func WithSeedLockTimeout(timeout time.Duration) SeederOption {
+	return func(s *seeder) {
+		if timeout >= 0 {
+			s.lockTimeout = timeout
+		}
+	}
+}
+
+// NewSeeder initializes a seeder with the specified database source, filesystem, and options.
+func NewSeeder(db MigrationSource, fs fs.FlexibleFS, options ...SeederOption) (Seeder, error) {
+	s := &seeder{
+		root:        "seeds",
+		table:       "seeds",
+		lockTimeout: 60 * time.Second,
+		files:       make([]seedFile, 0),
+		fs:          fs,
+		db:          db,
+	}
+
+	for _, opt := range options {
+		opt(s)
+	}
+
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *seeder) Load() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	files, err := s.fs.Lookup(s.root, `.*\.(csv|json|yaml|yml)`)
+	if err != nil {
+		return err
+	}
+
+	s.files = make([]seedFile, 0)
+	for _, path := range files {
+		content, err := s.fs.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		file, err := newSeedFile(path, content)
+		if err != nil {
+			return err
+		} else if file == nil {
+			continue
+		}
+
+		if dir := relativeDir(s.root, path); dir != "." {
+			file.name = dir + "/" + file.name
+		}
+		s.files = append(s.files, *file)
+	}
+
+	sort.SliceStable(s.files, func(i, j int) bool {
+		return s.files[i].timestamp < s.files[j].timestamp
+	})
+	return nil
+}
+
+func (s *seeder) Seed(env string) ([]Seeded, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+	return s.SeedContext(ctx, env)
+}
+
+func (s *seeder) SeedContext(ctx context.Context, env string) ([]Seeded, error) {
+	// Hot reload on dev mode
+	if s.dev {
+		if err := s.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// Acquire lock, concurrent table creation fails on Postgres
+	unlock, err := lockSource(ctx, s.db, "seed:"+s.table, s.lockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := s.initialize(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := s.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply pending and changed seeds in one transaction
+	result := make([]Seeded, 0)
+	err = s.db.Transaction(ctx, func(tx ExecutableScanner) error {
+		for _, file := range s.files {
+			sum, ok := applied[file.name]
+			if !file.Match(env) || sum == file.checksum || (ok && len(file.key) == 0) {
+				continue
+			}
+
+			for _, row := range file.rows {
+				if len(row) == 0 {
+					continue
+				}
+
+				if err := tx.Exec(ctx, s.upsert(file, row), s.values(file, row)...); err != nil {
+					return fmt.Errorf("seed %q: %w", file.name, err)
+				}
+			}
+
+			err := tx.Exec(ctx, s.compile(`DELETE FROM @table WHERE name = ?;`), file.name)
+			if err != nil {
+				return err
+			}
+			err = tx.Exec(ctx, s.compile(`INSERT INTO @table (name, checksum) VALUES (?, ?);`), file.name, file.checksum)
+			if err != nil {
+				return err
+			}
+
+			result = append(result, Seeded{
+				Name:  file.name,
+				Table: file.table,
+				Env:   file.env,
+				Rows:  len(file.rows),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// initialize creates the seeds tracking table.
+func (s *seeder) initialize(ctx context.Context) error {
+	return s.db.Exec(
+		ctx,
+		s.compile(`CREATE TABLE IF NOT EXISTS @table (
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY(name)
+		);`),
+	)
+}
+
+// applied returns the checksums of applied seeds by name.
+func (s *seeder) applied(ctx context.Context) (map[string]string, error) {
+	rows, err := s.db.Scan(ctx, s.compile(`SELECT name, checksum FROM @table;`))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]string)
+	for rows.Next() {
+		var name, sum string
+		if err := rows.Scan(&name, &sum); err != nil {
+			return nil, err
+		}
+		result[name] = sum
+	}
+	if err := rowsErr(rows); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// upsert builds the insert statement of the row, updating non-key columns on key conflict.
+func (s *seeder) upsert(file seedFile, row map[string]any) string {
+	columns := file.Columns(row)
+	quoted := make([]string, 0, len(columns))
+	placeholders := make([]string, 0, len(columns))
+	for i, column := range columns {
+		quoted = append(quoted, quoteIdentifier(s.db, column))
+		placeholders = append(placeholders, placeholder(s.db, i+1))
+	}
+
+	sql := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		quoteIdentifier(s.db, file.table),
+		strings.Join(quoted, ", "),
+		strings.Join(placeholders, ", "),
+	)
+	if len(file.key) == 0 {
+		return sql + ";"
+	}
+
+	// Update non-key columns
+	updates := make([]string, 0)
+	for _, column := range columns {
+		if slices.Contains(file.key, column) {
+			continue
+		}
+
+		name := quoteIdentifier(s.db, column)
+		if dialectOf(s.db) == DialectMySQL {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", name, name))
+		} else {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", name, name))
+		}
+	}
+
+	if dialectOf(s.db) == DialectMySQL {
+		if len(updates) == 0 {
+			return "INSERT IGNORE" + strings.TrimPrefix(sql, "INSERT") + ";"
+		}
+		return sql + " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ") + ";"
+	}
+
+	keys := make([]string, 0, len(file.key))
+	for _, column := range file.key {
+		keys = append(keys, quoteIdentifier(s.db, column))
+	}
+	if len(updates) == 0 {
+		return sql + fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING;", strings.Join(keys, ", "))
+	}
+	return sql + fmt.Sprintf(
+		" ON CONFLICT (%s) DO UPDATE SET %s;",
+		strings.Join(keys, ", "), strings.Join(updates, ", "),
+	)
+}
+
+// values returns the row values ordered by column.
+func (s *seeder) values(file seedFile, row map[string]any) []any {
+	columns := file.Columns(row)
+	values := make([]any, 0, len(columns))
+	for _, column := range columns {
+		values = append(values, row[column])
+	}
+	return values
+}
+
+// compile replaces the @table placeholder with the quoted seeds table name
+// and ? placeholders with the source bind parameters.
+func (s *seeder) compile(query string) string {
+	return compileQuery(s.db, s.table, query)
+}
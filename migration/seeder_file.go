@@ -0,0 +1,217 @@
+package migration
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// seedFile represents a parsed seed data file.
+type seedFile struct {
+	timestamp int64
+	name      string
+	table     string
+	env       string
+	checksum  string
+	key       []string
+	rows      []map[string]any
+}
+
+// Columns returns the sorted column names of the row.
+func (f seedFile) Columns(row map[string]any) []string {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// Match checks whether the seed runs in the environment.
+// Files without environment run in all environments.
+func (f seedFile) Match(env string) bool {
+	return f.env == "" || f.env == env
+}
+
+// seedFileName matches "timestamp-table[.env].ext" seed file names.
+var seedFileName = regexp.MustCompile(`^(\d+)-([a-zA-Z0-9_]+)(?:\.([a-zA-Z0-9_-]+))?\.(csv|json|yaml|yml)$`)
+
+// seedDocument is the object form of JSON and YAML seed files.
+type seedDocument struct {
+	Table string           `json:"table" yaml:"table"`
+	Key   []string         `json:"key" yaml:"key"`
+	Rows  []map[string]any `json:"rows" yaml:"rows"`
+}
+
+// newSeedFile parses a seed file. Returns nil for files not matching the seed file name.
+func newSeedFile(path string, content []byte) (*seedFile, error) {
+	matches := seedFileName.FindStringSubmatch(filepath.Base(path))
+	if len(matches) != 5 {
+		return nil, nil
+	}
+
+	timestamp, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	result := &seedFile{
+		timestamp: timestamp,
+		name:      filepath.Base(path),
+		table:     matches[2],
+		env:       matches[3],
+		checksum:  checksum(string(content)),
+	}
+
+	var doc seedDocument
+	switch matches[4] {
+	case "csv":
+		doc, err = parseSeedCSV(content)
+	case "json":
+		doc, err = parseSeedJSON(content)
+	default:
+		doc, err = parseSeedYAML(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse seed %q: %w", path, err)
+	}
+
+	if doc.Table != "" {
+		result.table = doc.Table
+	}
+	result.key = doc.Key
+	result.rows = doc.Rows
+	return result, nil
+}
+
+// parseSeedCSV parses CSV rows with header. Empty values are inserted as NULL.
+// Leading "# key: a, b" lines set the upsert key columns.
+func parseSeedCSV(content []byte) (seedDocument, error) {
+	var doc seedDocument
+	lines := strings.Split(string(content), "\n")
+	for len(lines) > 0 && strings.HasPrefix(strings.TrimSpace(lines[0]), "#") {
+		directive := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[0]), "#"))
+		if value, ok := strings.CutPrefix(directive, "key:"); ok {
+			doc.Key = splitList(value)
+		}
+		lines = lines[1:]
+	}
+
+	reader := csv.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	header, err := reader.Read()
+	if err == io.EOF {
+		return doc, nil
+	} else if err != nil {
+		return doc, err
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return doc, err
+		}
+
+		row := make(map[string]any, len(header))
+		for i, column := range header {
+			if record[i] == "" {
+				row[strings.TrimSpace(column)] = nil
+			} else {
+				row[strings.TrimSpace(column)] = record[i]
+			}
+		}
+		doc.Rows = append(doc.Rows, row)
+	}
+	return doc, nil
+}
+
+// parseSeedJSON parses an array of rows or a {"table", "key", "rows"} document.
+func parseSeedJSON(content []byte) (seedDocument, error) {
+	var doc seedDocument
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var err error
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = decoder.Decode(&doc.Rows)
+	} else {
+		err = decoder.Decode(&doc)
+	}
+	if err != nil {
+		return doc, err
+	}
+	return doc, normalizeSeedRows(doc.Rows)
+}
+
+// parseSeedYAML parses a sequence of rows or a {table, key, rows} document.
+func parseSeedYAML(content []byte) (seedDocument, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return seedDocument{}, err
+	}
+
+	var doc seedDocument
+	if len(node.Content) == 0 {
+		return doc, nil
+	}
+
+	var err error
+	if node.Content[0].Kind == yaml.SequenceNode {
+		err = node.Decode(&doc.Rows)
+	} else {
+		err = node.Decode(&doc)
+	}
+	if err != nil {
+		return doc, err
+	}
+	return doc, normalizeSeedRows(doc.Rows)
+}
+
+// normalizeSeedRows converts decoded values to bind parameters.
+// JSON numbers become int64 or float64, nested objects and arrays are encoded as JSON text.
+func normalizeSeedRows(rows []map[string]any) error {
+	for _, row := range rows {
+		for column, value := range row {
+			switch v := value.(type) {
+			case json.Number:
+				if i, err := v.Int64(); err == nil {
+					row[column] = i
+				} else if f, err := v.Float64(); err == nil {
+					row[column] = f
+				} else {
+					return err
+				}
+			case int:
+				row[column] = int64(v)
+			case map[string]any, []any:
+				encoded, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				row[column] = string(encoded)
+			}
+		}
+	}
+	return nil
+}
+
+// splitList splits comma separated values and removes empty ones.
+func splitList(value string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package migration_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-universal/sql/migration"
+)
+
+func TestSeeder(t *testing.T) {
+	fs := &MockFS{
+		files: map[string]string{
+			"seeds/1-roles.csv":      "# key: id\nid,title\n1,admin\n2,\n",
+			"seeds/2-users.dev.json": `{"key": ["id"], "rows": [{"id": 1, "name": "john", "meta": {"admin": true}}]}`,
+			"seeds/3-tags.yaml":      "- name: go\n- name: sql\n",
+			"seeds/README.md":        "# seeds",
+		},
+	}
+
+	t.Run("production skips dev seeds", func(t *testing.T) {
+		source := &MockSource{}
+		seeder, err := migration.NewSeeder(source, fs)
+		require.NoError(t, err)
+
+		result, err := seeder.Seed("")
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.Equal(t, "1-roles.csv", result[0].Name)
+		assert.Equal(t, "3-tags.yaml", result[1].Name)
+
+		assert.Contains(t, source.statements,
+			`INSERT INTO "roles" ("id", "title") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "title" = EXCLUDED."title";`,
+		)
+		assert.Contains(t, source.statements, `INSERT INTO "tags" ("name") VALUES ($1);`)
+		assert.Equal(t, []any{"2", nil}, source.inserts[1])
+	})
+
+	t.Run("dev includes dev seeds", func(t *testing.T) {
+		source := &MockSource{}
+		seeder, err := migration.NewSeeder(source, fs)
+		require.NoError(t, err)
+
+		result, err := seeder.Seed("dev")
+		require.NoError(t, err)
+		require.Len(t, result, 3)
+		assert.Equal(t, "users", result[1].Table)
+		assert.Contains(t, source.inserts, []any{int64(1), `{"admin":true}`, "john"})
+	})
+
+	t.Run("lock timeout", func(t *testing.T) {
+		source := &MockSource{held: true}
+		seeder, err := migration.NewSeeder(source, fs, migration.WithSeedLockTimeout(time.Second))
+		require.NoError(t, err)
+
+		_, err = seeder.Seed("")
+		require.ErrorIs(t, err, migration.ErrLockTimeout)
+		assert.ErrorContains(t, err, "waited 1s")
+		assert.Zero(t, source.created, "seeds table is created under the lock")
+	})
+}
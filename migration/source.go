@@ -1,6 +1,14 @@
 package migration
 
-import "context"
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrLockTimeout is returned when the migration lock can't be acquired within the wait timeout.
+var ErrLockTimeout = errors.New("migration lock not acquired")
 
 // MigrationSource defines methods for running database migrations within a transaction.
 type MigrationSource interface {
@@ -17,6 +25,82 @@ type MigrationSource interface {
 	Scan(ctx context.Context, sql string, arguments ...any) (Rows, error)
 }
 
+// MigrationLocker is implemented by sources supporting cross-process locks.
+// Sources without it run migrations without cross-process lock.
+type MigrationLocker interface {
+	// Lock acquires a cross-process lock identified by key, waiting at most timeout.
+	// Returns a function that releases the lock, or ErrLockTimeout if the lock is held by another process.
+	Lock(ctx context.Context, key string, timeout time.Duration) (func() error, error)
+}
+
+// lockSource acquires the lock on sources implementing MigrationLocker.
+// Other sources are not locked.
+func lockSource(ctx context.Context, db MigrationSource, key string, timeout time.Duration) (func() error, error) {
+	if locker, ok := db.(MigrationLocker); ok {
+		return locker.Lock(ctx, key, timeout)
+	}
+	return func() error { return nil }, nil
+}
+
+// IdentifierQuoter is implemented by sources quoting identifiers in their SQL dialect.
+// Identifiers of other sources are used as is.
+type IdentifierQuoter interface {
+	// QuoteIdentifier quotes a possibly schema qualified identifier (e.g. "schema.table").
+	QuoteIdentifier(name string) string
+}
+
+// quoteIdentifier quotes the identifier on sources implementing IdentifierQuoter.
+func quoteIdentifier(db MigrationSource, name string) string {
+	if quoter, ok := db.(IdentifierQuoter); ok {
+		return quoter.QuoteIdentifier(name)
+	}
+	return name
+}
+
+// PlaceholderFormatter is implemented by sources with dialect specific bind parameters (e.g. "$1").
+// Other sources use "?" placeholders.
+type PlaceholderFormatter interface {
+	// Placeholder returns the bind parameter placeholder for the 1-based argument index.
+	Placeholder(index int) string
+}
+
+// placeholder returns the bind parameter placeholder of the source for the 1-based argument index.
+func placeholder(db MigrationSource, index int) string {
+	if formatter, ok := db.(PlaceholderFormatter); ok {
+		return formatter.Placeholder(index)
+	}
+	return "?"
+}
+
+// DialectProvider is implemented by sources reporting their SQL dialect.
+// Scripts of other sources run as a single statement and dialect specific features
+// (analyzer, reversibility check, name column upgrade) are not available.
+type DialectProvider interface {
+	// Dialect returns the SQL dialect used to split migration scripts into statements.
+	Dialect() Dialect
+}
+
+// dialectOf returns the source dialect, empty if unknown.
+func dialectOf(db MigrationSource) Dialect {
+	if provider, ok := db.(DialectProvider); ok {
+		return provider.Dialect()
+	}
+	return ""
+}
+
+// splitScript splits the script into statements by the source dialect.
+// Scripts of sources without dialect run as a single statement.
+func splitScript(db MigrationSource, script string) []Statement {
+	if provider, ok := db.(DialectProvider); ok {
+		return SplitStatements(script, provider.Dialect())
+	}
+
+	if strings.TrimSpace(script) == "" {
+		return nil
+	}
+	return []Statement{{SQL: script, Line: 1}}
+}
+
 // ExecutableScanner represents an entity capable of executing SQL commands and scanning results.
 type ExecutableScanner interface {
 	// Exec executes a SQL command with the provided arguments.
@@ -42,3 +126,11 @@ type Rows interface {
 	// It prevents further row enumeration after being called.
 	Close()
 }
+
+// rowsErr returns the error encountered during iteration of rows implementing Err() error.
+func rowsErr(rows Rows) error {
+	if r, ok := rows.(interface{ Err() error }); ok {
+		return r.Err()
+	}
+	return nil
+}
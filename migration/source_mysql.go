@@ -2,7 +2,13 @@ package migration
 
 import (
 	"context"
+	"crypto/sha1"
 	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/go-universal/sql/mysql"
 )
@@ -44,6 +50,57 @@ func (ps *mysqlSource) Scan(c context.Context, s string, args ...any) (Rows, err
 	return &mysqlRows{rows: rows}, nil
 }
 
+func (ps *mysqlSource) Lock(c context.Context, key string, timeout time.Duration) (func() error, error) {
+	// Lock names are limited to 64 characters
+	if len(key) > 64 {
+		sum := sha1.Sum([]byte(key))
+		key = hex.EncodeToString(sum[:])
+	}
+
+	// Named locks are session level, so hold a dedicated connection until unlock
+	conn, err := ps.conn.Database().Conn(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked sql.NullInt64
+	err = conn.QueryRowContext(
+		c, `SELECT GET_LOCK(?, ?);`,
+		key, int64(math.Ceil(timeout.Seconds())),
+	).Scan(&locked)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if !locked.Valid || locked.Int64 != 1 {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %q is held by another process (waited %s)", ErrLockTimeout, key, timeout)
+	}
+
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?);`, key)
+		return err
+	}, nil
+}
+
+func (ps *mysqlSource) QuoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.ReplaceAll(part, "`", "``") + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+func (ps *mysqlSource) Placeholder(index int) string {
+	return "?"
+}
+
+func (ps *mysqlSource) Dialect() Dialect {
+	return DialectMySQL
+}
+
 // Implement ExecutableScanner for transaction
 type mysqlTX struct {
 	tx *sql.Tx
@@ -79,3 +136,7 @@ func (ps *mysqlRows) Scan(dest ...any) error {
 func (ps *mysqlRows) Close() {
 	ps.rows.Close()
 }
+
+func (ps *mysqlRows) Err() error {
+	return ps.rows.Err()
+}
@@ -2,11 +2,18 @@ package migration
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-universal/sql/postgres"
 	"github.com/jackc/pgx/v5"
 )
 
+// lockRetryInterval is the delay between advisory lock attempts.
+const lockRetryInterval = 200 * time.Millisecond
+
 type postgresSource struct {
 	conn postgres.Connection
 }
@@ -46,6 +53,62 @@ func (ps *postgresSource) Scan(c context.Context, s string, args ...any) (Rows,
 	return &postgresRows{rows: rows}, nil
 }
 
+func (ps *postgresSource) Lock(c context.Context, key string, timeout time.Duration) (func() error, error) {
+	// Advisory locks are session level, so hold a dedicated connection until unlock
+	conn, err := ps.conn.Database().Acquire(c)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var locked bool
+		err := conn.QueryRow(c, `SELECT pg_try_advisory_lock(hashtext($1));`, key).Scan(&locked)
+		if err != nil {
+			conn.Release()
+			return nil, err
+		}
+
+		if locked {
+			break
+		}
+
+		if !time.Now().Before(deadline) {
+			conn.Release()
+			return nil, fmt.Errorf("%w: %q is held by another process (waited %s)", ErrLockTimeout, key, timeout)
+		}
+
+		select {
+		case <-c.Done():
+			conn.Release()
+			return nil, c.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	return func() error {
+		defer conn.Release()
+		_, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext($1));`, key)
+		return err
+	}, nil
+}
+
+func (ps *postgresSource) QuoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+func (ps *postgresSource) Placeholder(index int) string {
+	return "$" + strconv.Itoa(index)
+}
+
+func (ps *postgresSource) Dialect() Dialect {
+	return DialectPostgres
+}
+
 // Implement ExecutableScanner for transaction
 type postgresTx struct {
 	tx pgx.Tx
@@ -81,3 +144,7 @@ func (ps *postgresRows) Scan(dest ...any) error {
 func (ps *postgresRows) Close() {
 	ps.rows.Close()
 }
+
+func (ps *postgresRows) Err() error {
+	return ps.rows.Err()
+}
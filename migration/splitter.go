@@ -0,0 +1,241 @@
+package migration
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Dialect identifies the SQL syntax of a migration source.
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+)
+
+// Statement is a single SQL statement of a script.
+type Statement struct {
+	SQL  string
+	Line int // 1-based line of the statement in the script.
+}
+
+// SplitStatements splits a script into statements by the ";" delimiter.
+// Delimiters inside string literals, quoted identifiers and comments are ignored.
+// Postgres dollar quoted bodies ($$ ... $$, $fn$ ... $fn$) and
+// MySQL "DELIMITER //" directives are supported. Comment only statements are skipped.
+func SplitStatements(script string, dialect Dialect) []Statement {
+	s := &splitter{
+		script:    script,
+		dialect:   dialect,
+		delimiter: ";",
+		line:      1,
+		result:    make([]Statement, 0),
+	}
+	s.split()
+	return s.result
+}
+
+type splitter struct {
+	script    string
+	dialect   Dialect
+	delimiter string
+	line      int
+	pos       int
+	start     int // Line of the current statement, 0 if no content found yet.
+	buf       strings.Builder
+	result    []Statement
+}
+
+func (s *splitter) split() {
+	for s.pos < len(s.script) {
+		rest := s.script[s.pos:]
+		c := rest[0]
+
+		switch {
+		case s.dialect == DialectMySQL && s.start == 0 && isDelimiterDirective(rest):
+			s.readDelimiter()
+		case strings.HasPrefix(rest, s.delimiter):
+			s.pos += len(s.delimiter)
+			s.flush()
+		case strings.HasPrefix(rest, "--") || (s.dialect == DialectMySQL && c == '#'):
+			s.readLineComment()
+		case strings.HasPrefix(rest, "/*"):
+			s.readBlockComment()
+		case c == '\'' || c == '"' || (s.dialect == DialectMySQL && c == '`'):
+			s.mark()
+			s.readQuoted(c)
+		case s.dialect == DialectPostgres && c == '$' && dollarTag(rest) != "":
+			s.mark()
+			s.readDollarQuoted(dollarTag(rest))
+		default:
+			if !unicode.IsSpace(rune(c)) {
+				s.mark()
+			}
+			s.write(c)
+			s.pos++
+		}
+	}
+	s.flush()
+}
+
+// mark records the current line as statement start on the first content.
+func (s *splitter) mark() {
+	if s.start == 0 {
+		s.start = s.line
+	}
+}
+
+// write appends a byte to the current statement and tracks lines.
+func (s *splitter) write(c byte) {
+	if c == '\n' {
+		s.line++
+	}
+	s.buf.WriteByte(c)
+}
+
+// flush appends the current statement to result.
+func (s *splitter) flush() {
+	sql := strings.TrimSpace(s.buf.String())
+	if s.start != 0 && sql != "" {
+		s.result = append(s.result, Statement{SQL: sql, Line: s.start})
+	}
+	s.buf.Reset()
+	s.start = 0
+}
+
+// readDelimiter consumes a MySQL "DELIMITER xx" directive line.
+func (s *splitter) readDelimiter() {
+	end := strings.IndexByte(s.script[s.pos:], '\n')
+	if end < 0 {
+		end = len(s.script) - s.pos
+	}
+
+	if delimiter := strings.TrimSpace(s.script[s.pos+len("DELIMITER") : s.pos+end]); delimiter != "" {
+		s.delimiter = delimiter
+	}
+	s.pos += end
+}
+
+// readLineComment consumes a comment until the end of line.
+func (s *splitter) readLineComment() {
+	for s.pos < len(s.script) && s.script[s.pos] != '\n' {
+		if s.start != 0 {
+			s.buf.WriteByte(s.script[s.pos])
+		}
+		s.pos++
+	}
+}
+
+// readBlockComment consumes a block comment, nested on Postgres.
+// MySQL executable comments (/*! ... */ and /*+ ... */) are statement content.
+func (s *splitter) readBlockComment() {
+	keep := s.start != 0
+	if s.dialect == DialectMySQL && len(s.script) > s.pos+2 && strings.ContainsRune("!+", rune(s.script[s.pos+2])) {
+		s.mark()
+		keep = true
+	}
+
+	depth := 0
+	for s.pos < len(s.script) {
+		rest := s.script[s.pos:]
+		if strings.HasPrefix(rest, "/*") && (depth == 0 || s.dialect == DialectPostgres) {
+			depth++
+			s.writeComment("/*", keep)
+			continue
+		}
+
+		if strings.HasPrefix(rest, "*/") {
+			depth--
+			s.writeComment("*/", keep)
+			if depth == 0 {
+				return
+			}
+			continue
+		}
+
+		s.writeComment(rest[:1], keep)
+	}
+}
+
+// writeComment consumes comment text, keeping it in statement if required.
+func (s *splitter) writeComment(text string, keep bool) {
+	for i := 0; i < len(text); i++ {
+		if keep {
+			s.write(text[i])
+		} else if text[i] == '\n' {
+			s.line++
+		}
+	}
+	s.pos += len(text)
+}
+
+// readQuoted consumes a string literal or quoted identifier.
+// Doubled quotes and, on MySQL strings or Postgres E” strings, backslashes escape.
+func (s *splitter) readQuoted(quote byte) {
+	backslash := quote != '`' && s.dialect == DialectMySQL
+	if s.dialect == DialectPostgres && quote == '\'' && s.pos > 0 && (s.script[s.pos-1] == 'E' || s.script[s.pos-1] == 'e') {
+		backslash = true
+	}
+
+	s.write(quote)
+	s.pos++
+	for s.pos < len(s.script) {
+		c := s.script[s.pos]
+		switch {
+		case backslash && c == '\\' && s.pos+1 < len(s.script):
+			s.write(c)
+			s.write(s.script[s.pos+1])
+			s.pos += 2
+		case c == quote && s.pos+1 < len(s.script) && s.script[s.pos+1] == quote:
+			s.write(c)
+			s.write(c)
+			s.pos += 2
+		case c == quote:
+			s.write(c)
+			s.pos++
+			return
+		default:
+			s.write(c)
+			s.pos++
+		}
+	}
+}
+
+// readDollarQuoted consumes a Postgres dollar quoted string including its tags.
+func (s *splitter) readDollarQuoted(tag string) {
+	end := strings.Index(s.script[s.pos+len(tag):], tag)
+	if end < 0 {
+		end = len(s.script) - s.pos
+	} else {
+		end += len(tag) * 2
+	}
+
+	for i := 0; i < end; i++ {
+		s.write(s.script[s.pos+i])
+	}
+	s.pos += end
+}
+
+// dollarTag returns the Postgres dollar quote tag ($$ or $name$) at the start of text.
+func dollarTag(text string) string {
+	for i := 1; i < len(text); i++ {
+		c := rune(text[i])
+		switch {
+		case c == '$':
+			return text[:i+1]
+		case c == '_' || unicode.IsLetter(c) || (i > 1 && unicode.IsDigit(c)):
+			continue
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// isDelimiterDirective reports whether text starts with a MySQL "DELIMITER" directive.
+func isDelimiterDirective(text string) bool {
+	const keyword = "DELIMITER"
+	return len(text) > len(keyword) &&
+		strings.EqualFold(text[:len(keyword)], keyword) &&
+		(text[len(keyword)] == ' ' || text[len(keyword)] == '\t')
+}
@@ -0,0 +1,79 @@
+package migration_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-universal/sql/migration"
+)
+
+func TestSplitStatements(t *testing.T) {
+	t.Run("Basic", func(t *testing.T) {
+		statements := migration.SplitStatements(
+			"-- leading comment\nCREATE TABLE a (id INT);\n\nINSERT INTO a VALUES (1); -- trailing\n",
+			migration.DialectPostgres,
+		)
+		assert.Equal(t, []migration.Statement{
+			{SQL: "CREATE TABLE a (id INT)", Line: 2},
+			{SQL: "INSERT INTO a VALUES (1)", Line: 4},
+		}, statements)
+	})
+
+	t.Run("Literals", func(t *testing.T) {
+		statements := migration.SplitStatements(
+			`INSERT INTO a VALUES ('x;y', 'it''s', "c;d"); SELECT E'a\';b'; /* ; */ SELECT 1`,
+			migration.DialectPostgres,
+		)
+		assert.Equal(t, []string{
+			`INSERT INTO a VALUES ('x;y', 'it''s', "c;d")`,
+			`SELECT E'a\';b'`,
+			`SELECT 1`,
+		}, sqls(statements))
+	})
+
+	t.Run("DollarQuoting", func(t *testing.T) {
+		statements := migration.SplitStatements(
+			"CREATE FUNCTION f() RETURNS INT AS $$\nBEGIN\n  RETURN 1;\nEND;\n$$ LANGUAGE plpgsql;\n"+
+				"CREATE FUNCTION g() RETURNS TEXT AS $body$ SELECT '$$;' $body$ LANGUAGE sql;\n"+
+				"SELECT $1::int;",
+			migration.DialectPostgres,
+		)
+		assert.Equal(t, []migration.Statement{
+			{SQL: "CREATE FUNCTION f() RETURNS INT AS $$\nBEGIN\n  RETURN 1;\nEND;\n$$ LANGUAGE plpgsql", Line: 1},
+			{SQL: "CREATE FUNCTION g() RETURNS TEXT AS $body$ SELECT '$$;' $body$ LANGUAGE sql", Line: 6},
+			{SQL: "SELECT $1::int", Line: 7},
+		}, statements)
+	})
+
+	t.Run("Delimiter", func(t *testing.T) {
+		statements := migration.SplitStatements(
+			"DROP PROCEDURE IF EXISTS p;\nDELIMITER //\nCREATE PROCEDURE p()\nBEGIN\n  SELECT 1; # inner\nEND //\nDELIMITER ;\nCALL p();",
+			migration.DialectMySQL,
+		)
+		assert.Equal(t, []migration.Statement{
+			{SQL: "DROP PROCEDURE IF EXISTS p", Line: 1},
+			{SQL: "CREATE PROCEDURE p()\nBEGIN\n  SELECT 1; # inner\nEND", Line: 3},
+			{SQL: "CALL p()", Line: 8},
+		}, statements)
+	})
+
+	t.Run("MySQLQuotes", func(t *testing.T) {
+		statements := migration.SplitStatements(
+			"INSERT INTO `a;b` VALUES ('x\\';y'); /*!40101 SET NAMES utf8 */;",
+			migration.DialectMySQL,
+		)
+		assert.Equal(t, []string{
+			"INSERT INTO `a;b` VALUES ('x\\';y')",
+			"/*!40101 SET NAMES utf8 */",
+		}, sqls(statements))
+	})
+}
+
+func sqls(statements []migration.Statement) []string {
+	result := make([]string, 0)
+	for _, statement := range statements {
+		result = append(result, statement.SQL)
+	}
+	return result
+}
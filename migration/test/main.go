@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"path"
 	"strings"
 
@@ -18,14 +20,26 @@ func main() {
 	fs := CreateFS()
 	conn := CreateConnection()
 	mig := CreateMigration(fs, conn)
+	seeder, err := migration.NewSeeder(migration.NewMySQLSource(conn), fs, migration.WithSeedRoot("seeds"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	cmd := migration.NewMigrationCLI(
 		mig,
 		migration.WithDefaultStages("table", "index"),
 		migration.WithOutputPath("database/migrations"),
 		migration.WithNewCMD(true),
+		migration.WithSeeder(seeder),
 	)
 
-	cmd.ExecuteContext(context.Background())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		stop()
+		os.Exit(1)
+	}
 }
 
 func main2() {
@@ -1,10 +1,16 @@
 package migration
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/user"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
+	"text/template"
 
 	"github.com/spf13/cobra"
 )
@@ -34,6 +40,68 @@ func normalizePath(path ...string) string {
 	return filepath.ToSlash(filepath.Clean(filepath.Join(path...)))
 }
 
+// render executes the content as text/template with data.
+// Missing variables are reported as error.
+func render(name, content string, data any) ([]byte, error) {
+	tpl, err := template.New(name).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compileQuery replaces the @table placeholder with the quoted table name
+// and ? placeholders with the source bind parameters.
+func compileQuery(db MigrationSource, table, query string) string {
+	query = strings.ReplaceAll(query, "@table", quoteIdentifier(db, table))
+
+	var builder strings.Builder
+	counter := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			counter++
+			builder.WriteString(placeholder(db, counter))
+		} else {
+			builder.WriteByte(query[i])
+		}
+	}
+	return builder.String()
+}
+
+// currentUser returns the operating system user name, empty if unknown.
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+
+	for _, key := range []string{"USER", "USERNAME"} {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// currentHost returns the host name, empty if unknown.
+func currentHost() string {
+	host, _ := os.Hostname()
+	return host
+}
+
+// relativeDir returns the directory of file path relative to root.
+func relativeDir(root, file string) string {
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return "."
+	}
+	return filepath.ToSlash(filepath.Dir(rel))
+}
+
 // alphaNum extract alpha and numbers from string [a-zA-Z0-9].
 func alphaNum(s string, includes ...string) string {
 	pattern := "[^a-zA-Z0-9" + strings.Join(includes, "") + "]"
@@ -49,6 +117,12 @@ func slugify(parts ...string) string {
 	return rx.ReplaceAllString(strings.ToLower(normalized), "-")
 }
 
+// checksum returns the hex encoded sha256 hash of a migration script.
+func checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
 // getFlag get flag from input command.
 func getFlag(cmd *cobra.Command, name string) string {
 	if v, err := cmd.Flags().GetString(name); err == nil {
@@ -56,3 +130,19 @@ func getFlag(cmd *cobra.Command, name string) string {
 	}
 	return ""
 }
+
+// getBoolFlag get boolean flag from input command.
+func getBoolFlag(cmd *cobra.Command, name string) bool {
+	if v, err := cmd.Flags().GetBool(name); err == nil {
+		return v
+	}
+	return false
+}
+
+// getIntFlag get integer flag from input command.
+func getIntFlag(cmd *cobra.Command, name string) int {
+	if v, err := cmd.Flags().GetInt(name); err == nil {
+		return v
+	}
+	return 0
+}